@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/zzhtl/go-mountain/internal/config"
 	"github.com/zzhtl/go-mountain/internal/db"
@@ -13,6 +16,12 @@ import (
 )
 
 func main() {
+	// 主密钥轮换：go run ./cmd/migrate -rotate-secret-key
+	// 新主密钥从环境变量 SECRET_NEW_MASTER_KEY 或 -new-key-file 指定的文件读取，旧主密钥即当前配置的 SECRET_MASTER_KEY
+	rotate := flag.Bool("rotate-secret-key", false, "使用新主密钥重新加密全部敏感配置")
+	newKeyFile := flag.String("new-key-file", "", "新主密钥文件路径（默认读取环境变量 SECRET_NEW_MASTER_KEY）")
+	flag.Parse()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
@@ -25,6 +34,11 @@ func main() {
 
 	log.Println("开始数据库迁移...")
 
+	// 金额字段由元（decimal）迁移为分（bigint），需在 AutoMigrate 之前执行
+	if err := db.MigrateMoneyColumns(database); err != nil {
+		log.Fatalf("迁移金额字段失败: %v", err)
+	}
+
 	// 自动迁移所有表结构
 	if err := database.AutoMigrate(
 		&model.BackendUser{},
@@ -35,8 +49,20 @@ func main() {
 		&model.Column{},
 		&model.User{},
 		&model.Activity{},
+		&model.ActivityTicket{},
+		&model.ActivityTemplate{},
+		&model.ActivitySeries{},
 		&model.Registration{},
+		&model.RegistrationOrder{},
+		&model.ActivityReview{},
+		&model.VolunteerHour{},
+		&model.VolunteerCertificate{},
+		&model.DonationCampaign{},
+		&model.Donation{},
+		&model.DonationReceipt{},
 		&model.Payment{},
+		&model.Refund{},
+		&model.PaymentReconciliation{},
 		&model.CodegenConfig{},
 		&model.OperationLog{},
 		&model.SystemConfig{},
@@ -45,6 +71,15 @@ func main() {
 	}
 	log.Println("表结构迁移完成")
 
+	// 为启用退款流水前的历史退款补登流水
+	if err := db.BackfillRefunds(database); err != nil {
+		log.Fatalf("补登退款流水失败: %v", err)
+	}
+	// 为记录发布时间前已发布的活动补登发布时间
+	if err := db.BackfillPublishedAt(database); err != nil {
+		log.Fatalf("补登活动发布时间失败: %v", err)
+	}
+
 	ctx := context.Background()
 
 	// 初始化默认角色
@@ -101,5 +136,55 @@ func main() {
 		log.Println("管理员账号已存在，跳过创建")
 	}
 
+	// 敏感配置：初始化默认配置项并加密历史明文
+	masterKey, err := cfg.Secret.LoadMasterKey()
+	if err != nil {
+		log.Fatalf("读取主密钥失败: %v", err)
+	}
+	secretCipher, err := newSecretCipher(masterKey)
+	if err != nil {
+		log.Fatalf("初始化主密钥失败: %v", err)
+	}
+	systemConfigSvc := service.NewSystemConfigService(database, secretCipher)
+	systemConfigSvc.InitDefaultConfigs(ctx)
+	if secretCipher == nil {
+		log.Println("未配置主密钥（SECRET_MASTER_KEY），跳过敏感配置加密")
+	} else if n, err := systemConfigSvc.EncryptPlainSecrets(ctx); err != nil {
+		log.Fatalf("加密历史敏感配置失败: %v", err)
+	} else {
+		log.Printf("敏感配置加密完成，本次加密 %d 项", n)
+	}
+
+	if *rotate {
+		newKey := os.Getenv("SECRET_NEW_MASTER_KEY")
+		if *newKeyFile != "" {
+			data, err := os.ReadFile(*newKeyFile)
+			if err != nil {
+				log.Fatalf("读取新主密钥失败: %v", err)
+			}
+			newKey = strings.TrimSpace(string(data))
+		}
+		if newKey == "" {
+			log.Fatal("未提供新主密钥（SECRET_NEW_MASTER_KEY 或 -new-key-file）")
+		}
+		next, err := newSecretCipher(newKey)
+		if err != nil {
+			log.Fatalf("初始化新主密钥失败: %v", err)
+		}
+		n, err := systemConfigSvc.RotateSecrets(ctx, next)
+		if err != nil {
+			log.Fatalf("轮换主密钥失败: %v", err)
+		}
+		log.Printf("主密钥轮换完成，已重新加密 %d 项敏感配置，请将 SECRET_MASTER_KEY 更新为新主密钥后重启服务", n)
+	}
+
 	log.Println("数据库迁移全部完成！")
 }
+
+// newSecretCipher 创建敏感配置加密器，主密钥为空时返回 nil
+func newSecretCipher(masterKey string) (*crypto.Cipher, error) {
+	if masterKey == "" {
+		return nil, nil
+	}
+	return crypto.NewCipher(masterKey)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/zzhtl/go-mountain/internal/config"
 	"github.com/zzhtl/go-mountain/internal/db"
 	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/crypto"
 	"github.com/zzhtl/go-mountain/internal/server"
 	"github.com/zzhtl/go-mountain/internal/service"
 )
@@ -24,6 +25,11 @@ func main() {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 
+	// 金额字段由元（decimal）迁移为分（bigint），需在 AutoMigrate 之前执行
+	if err := db.MigrateMoneyColumns(database); err != nil {
+		log.Fatalf("迁移金额字段失败: %v", err)
+	}
+
 	// 自动迁移表结构
 	if err := database.AutoMigrate(
 		&model.BackendUser{},
@@ -34,8 +40,20 @@ func main() {
 		&model.Column{},
 		&model.User{},
 		&model.Activity{},
+		&model.ActivityTicket{},
+		&model.ActivityTemplate{},
+		&model.ActivitySeries{},
 		&model.Registration{},
+		&model.RegistrationOrder{},
+		&model.ActivityReview{},
+		&model.VolunteerHour{},
+		&model.VolunteerCertificate{},
+		&model.DonationCampaign{},
+		&model.Donation{},
+		&model.DonationReceipt{},
 		&model.Payment{},
+		&model.Refund{},
+		&model.PaymentReconciliation{},
 		&model.CodegenConfig{},
 		&model.OperationLog{},
 		&model.SystemConfig{},
@@ -43,6 +61,15 @@ func main() {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
 
+	// 为启用退款流水前的历史退款补登流水
+	if err := db.BackfillRefunds(database); err != nil {
+		log.Fatalf("补登退款流水失败: %v", err)
+	}
+	// 为记录发布时间前已发布的活动补登发布时间
+	if err := db.BackfillPublishedAt(database); err != nil {
+		log.Fatalf("补登活动发布时间失败: %v", err)
+	}
+
 	// 初始化默认数据
 	ctx := context.Background()
 	roleSvc := service.NewRoleService(database)
@@ -55,11 +82,30 @@ func main() {
 		log.Printf("初始化默认菜单失败: %v", err)
 	}
 
-	systemConfigSvc := service.NewSystemConfigService(database)
+	// 敏感配置加密器，未配置主密钥时为空（敏感配置无法保存）
+	var secretCipher *crypto.Cipher
+	masterKey, err := cfg.Secret.LoadMasterKey()
+	if err != nil {
+		log.Fatalf("读取主密钥失败: %v", err)
+	}
+	if masterKey != "" {
+		if secretCipher, err = crypto.NewCipher(masterKey); err != nil {
+			log.Fatalf("初始化主密钥失败: %v", err)
+		}
+	} else {
+		log.Println("未配置主密钥（SECRET_MASTER_KEY），敏感配置将无法保存")
+	}
+
+	systemConfigSvc := service.NewSystemConfigService(database, secretCipher)
 	systemConfigSvc.InitDefaultConfigs(ctx)
+	if n, err := systemConfigSvc.EncryptPlainSecrets(ctx); err != nil {
+		log.Printf("加密历史敏感配置失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已加密 %d 项历史明文敏感配置", n)
+	}
 
 	// 启动服务器
-	srv := server.NewServer(database, cfg)
+	srv := server.NewServer(database, cfg, systemConfigSvc)
 	if err := srv.Run(); err != nil {
 		log.Fatalf("服务器运行失败: %v", err)
 	}
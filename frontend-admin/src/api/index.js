@@ -67,20 +67,97 @@ export const activityApi = {
   create: data => request.post('/api/admin/activities/', data),
   update: (id, data) => request.put(`/api/admin/activities/${id}`, data),
   delete: id => request.delete(`/api/admin/activities/${id}`),
-  updateStatus: (id, data) => request.put(`/api/admin/activities/${id}/status`, data)
+  updateStatus: (id, data) => request.put(`/api/admin/activities/${id}/status`, data),
+  tickets: id => request.get(`/api/admin/activities/${id}/tickets`),
+  saveTickets: (id, data) => request.put(`/api/admin/activities/${id}/tickets`, data),
+  checkin: (id, data) => request.post(`/api/admin/activities/${id}/checkin`, data),
+  attendance: id => request.get(`/api/admin/activities/${id}/attendance`),
+  clone: (id, data) => request.post(`/api/admin/activities/${id}/clone`, data),
+  updateFuture: (id, data) => request.put(`/api/admin/activities/${id}`, data, { params: { scope: 'future' } })
+}
+
+// ==================== 活动评价 ====================
+export const activityReviewApi = {
+  list: params => request.get('/api/admin/activity-reviews/', { params }),
+  updateStatus: (id, data) => request.put(`/api/admin/activity-reviews/${id}/status`, data),
+  setFeatured: (id, data) => request.put(`/api/admin/activity-reviews/${id}/featured`, data)
+}
+
+// ==================== 志愿时长 ====================
+export const volunteerHourApi = {
+  list: params => request.get('/api/admin/volunteer-hours/', { params }),
+  create: data => request.post('/api/admin/volunteer-hours/', data),
+  delete: id => request.delete(`/api/admin/volunteer-hours/${id}`),
+  settle: data => request.post('/api/admin/volunteer-hours/settle', data),
+  totals: params => request.get('/api/admin/volunteer-hours/totals', { params })
+}
+
+// ==================== 募捐 ====================
+export const donationCampaignApi = {
+  list: params => request.get('/api/admin/donation-campaigns/', { params }),
+  get: id => request.get(`/api/admin/donation-campaigns/${id}`),
+  create: data => request.post('/api/admin/donation-campaigns/', data),
+  update: (id, data) => request.put(`/api/admin/donation-campaigns/${id}`, data),
+  delete: id => request.delete(`/api/admin/donation-campaigns/${id}`),
+  updateStatus: (id, data) => request.put(`/api/admin/donation-campaigns/${id}/status`, data)
+}
+
+export const donationApi = {
+  list: params => request.get('/api/admin/donations/', { params })
+}
+
+export const donationReceiptApi = {
+  list: params => request.get('/api/admin/donation-receipts/', { params }),
+  download: id => request.get(`/api/admin/donation-receipts/${id}/pdf`, { responseType: 'blob' }),
+  void: (id, data) => request.put(`/api/admin/donation-receipts/${id}/void`, data)
+}
+
+// ==================== 活动模板 ====================
+export const activityTemplateApi = {
+  list: params => request.get('/api/admin/activity-templates/', { params }),
+  get: id => request.get(`/api/admin/activity-templates/${id}`),
+  create: data => request.post('/api/admin/activity-templates/', data),
+  update: (id, data) => request.put(`/api/admin/activity-templates/${id}`, data),
+  delete: id => request.delete(`/api/admin/activity-templates/${id}`),
+  generate: (id, data) => request.post(`/api/admin/activity-templates/${id}/generate`, data)
 }
 
 // ==================== 报名管理 ====================
 export const registrationApi = {
   list: params => request.get('/api/admin/registrations/', { params }),
-  get: id => request.get(`/api/admin/registrations/${id}`)
+  get: id => request.get(`/api/admin/registrations/${id}`),
+  create: data => request.post('/api/admin/registrations/', data),
+  update: (id, data) => request.put(`/api/admin/registrations/${id}`, data),
+  cancel: (id, data) => request.put(`/api/admin/registrations/${id}/cancel`, data),
+  markPaid: id => request.put(`/api/admin/registrations/${id}/paid`),
+  approve: id => request.put(`/api/admin/registrations/${id}/approve`),
+  reject: (id, data) => request.put(`/api/admin/registrations/${id}/reject`, data),
+  exportColumns: params => request.get('/api/admin/registrations/export-columns', { params }),
+  export: params => request.get('/api/admin/registrations/export', { params, responseType: 'blob', timeout: 0 })
 }
 
 // ==================== 支付管理 ====================
 export const paymentApi = {
   list: params => request.get('/api/admin/payments/', { params }),
   get: id => request.get(`/api/admin/payments/${id}`),
-  refund: id => request.put(`/api/admin/payments/${id}/refund`)
+  refund: (id, data) => request.put(`/api/admin/payments/${id}/refund`, data),
+  refunds: params => request.get('/api/admin/payments/refunds', { params })
+}
+
+// ==================== 支付对账 ====================
+export const reconciliationApi = {
+  list: params => request.get('/api/admin/payment-reconciliations/', { params }),
+  get: id => request.get(`/api/admin/payment-reconciliations/${id}`),
+  run: data => request.post('/api/admin/payment-reconciliations/', data)
+}
+
+// ==================== 财务报表 ====================
+export const financialReportApi = {
+  summary: params => request.get('/api/admin/financial-reports/summary', { params }),
+  revenue: params => request.get('/api/admin/financial-reports/revenue', { params }),
+  bizTypes: params => request.get('/api/admin/financial-reports/biz-types', { params }),
+  activities: params => request.get('/api/admin/financial-reports/activities', { params }),
+  export: params => request.get('/api/admin/financial-reports/export', { params, responseType: 'blob' })
 }
 
 // ==================== 小程序用户 ====================
@@ -95,6 +172,7 @@ export const userApi = {
 export const systemConfigApi = {
   list: params => request.get('/api/admin/system-configs/', { params }),
   groups: () => request.get('/api/admin/system-configs/groups'),
+  schema: () => request.get('/api/admin/system-configs/schema'),
   save: data => request.post('/api/admin/system-configs/', data),
   batchSave: data => request.post('/api/admin/system-configs/batch', data),
   delete: key => request.delete('/api/admin/system-configs/', { params: { key } })
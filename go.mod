@@ -7,6 +7,7 @@ require (
 	github.com/ArtisanCloud/PowerWeChat/v3 v3.4.8
 	github.com/gin-gonic/gin v1.10.0
 	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/spf13/viper v1.20.1
 	golang.org/x/crypto v0.49.0
 	gorm.io/driver/postgres v1.6.0
@@ -35,12 +35,34 @@ type WechatConfig struct {
 	NotifyURL        string `mapstructure:"notify_url"`
 }
 
+// SecretConfig 敏感配置加密的主密钥，可直接配置或指定密钥文件（环境变量 SECRET_MASTER_KEY / SECRET_MASTER_KEY_FILE）
+type SecretConfig struct {
+	MasterKey     string `mapstructure:"master_key"`
+	MasterKeyFile string `mapstructure:"master_key_file"`
+}
+
+// LoadMasterKey 读取主密钥，优先使用 master_key，其次读取 master_key_file；均未配置时返回空字符串
+func (c SecretConfig) LoadMasterKey() (string, error) {
+	if c.MasterKey != "" {
+		return c.MasterKey, nil
+	}
+	if c.MasterKeyFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(c.MasterKeyFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // Config 全局配置
 type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Wechat   WechatConfig   `mapstructure:"wechat"`
+	Secret   SecretConfig   `mapstructure:"secret"`
 }
 
 // LoadConfig 从配置文件和环境变量加载配置
@@ -58,6 +80,9 @@ func LoadConfig() (*Config, error) {
 
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	// 主密钥通常只通过环境变量提供，需先登记 key 才能被 Unmarshal 读取
+	viper.SetDefault("secret.master_key", "")
+	viper.SetDefault("secret.master_key_file", "")
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+)
+
+// moneyColumn 由元（decimal）改为分（bigint）存储的金额字段
+type moneyColumn struct {
+	model  any
+	table  string
+	column string
+}
+
+var moneyColumns = []moneyColumn{
+	{&model.Activity{}, "activities", "price"},
+	{&model.ActivityTemplate{}, "activity_templates", "price"},
+	{&model.ActivityTicket{}, "activity_tickets", "price"},
+	{&model.Registration{}, "registrations", "amount"},
+	{&model.Registration{}, "registrations", "refund_amount"},
+	{&model.RegistrationOrder{}, "registration_orders", "total_amount"},
+	{&model.Payment{}, "payments", "amount"},
+	{&model.Payment{}, "payments", "refund_amount"},
+	{&model.DonationCampaign{}, "donation_campaigns", "goal_amount"},
+	{&model.Donation{}, "donations", "amount"},
+	{&model.DonationReceipt{}, "donation_receipts", "amount"},
+}
+
+// MigrateMoneyColumns 将历史的 decimal 金额列换算为分并改为 bigint
+// 需在 AutoMigrate 之前执行；已是整数类型的列会跳过，可重复执行
+func MigrateMoneyColumns(db *gorm.DB) error {
+	for _, mc := range moneyColumns {
+		decimal, err := isDecimalColumn(db, mc.table, mc.column)
+		if err != nil {
+			return err
+		}
+		if !decimal {
+			continue
+		}
+
+		if err := migrateMoneyColumn(db, mc); err != nil {
+			return fmt.Errorf("迁移金额字段 %s.%s 失败: %w", mc.table, mc.column, err)
+		}
+		log.Printf("金额字段 %s.%s 已由元转换为分", mc.table, mc.column)
+	}
+	return nil
+}
+
+// isDecimalColumn 判断列是否仍为 decimal/numeric 类型，表或列不存在时返回 false
+func isDecimalColumn(db *gorm.DB, table, column string) (bool, error) {
+	if !db.Migrator().HasTable(table) {
+		return false, nil
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false, err
+	}
+	for _, ct := range columnTypes {
+		if ct.Name() != column {
+			continue
+		}
+		typ := strings.ToLower(ct.DatabaseTypeName())
+		return strings.HasPrefix(typ, "decimal") || strings.HasPrefix(typ, "numeric"), nil
+	}
+	return false, nil
+}
+
+func migrateMoneyColumn(db *gorm.DB, mc moneyColumn) error {
+	if db.Dialector.Name() == "postgres" {
+		// 单条 ALTER 语句内完成换算与改类型，默认值由后续 AutoMigrate 补齐
+		return db.Exec(fmt.Sprintf(
+			"ALTER TABLE %[1]s ALTER COLUMN %[2]s DROP DEFAULT, ALTER COLUMN %[2]s TYPE bigint USING ROUND(%[2]s * 100)::bigint",
+			mc.table, mc.column,
+		)).Error
+	}
+
+	// SQLite 不支持 ALTER COLUMN，先换算数值再按模型重建列定义，两步在同一事务中完成
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf(
+			"UPDATE %[1]s SET %[2]s = CAST(ROUND(%[2]s * 100) AS INTEGER) WHERE %[2]s IS NOT NULL",
+			mc.table, mc.column,
+		)).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().AlterColumn(mc.model, mc.column)
+	})
+}
+
+// BackfillRefunds 为启用退款流水前已退款的支付补登一条退款成功的流水，保证累计退款金额可由流水汇总得出
+// 早期全额退款只将支付标记为已退款而未记录累计退款金额，按支付金额补登并回填累计退款金额；
+// 退款时间缺失时以支付记录的更新时间为准。需在 AutoMigrate 之后执行，已有流水的支付会跳过，可重复执行
+func BackfillRefunds(db *gorm.DB) error {
+	var pays []model.Payment
+	err := db.Where("(refund_amount > 0 OR status = 2) AND NOT EXISTS (SELECT 1 FROM refunds WHERE refunds.payment_id = payments.id)").
+		Find(&pays).Error
+	if err != nil {
+		return err
+	}
+
+	for _, pay := range pays {
+		amount := pay.RefundAmount
+		if amount == 0 {
+			amount = pay.Amount
+		}
+		successAt := pay.RefundAt
+		if successAt == nil {
+			successAt = &pay.UpdatedAt
+		}
+		refund := model.Refund{
+			OutRefundNo: "H" + pay.OrderNo,
+			PaymentID:   pay.ID,
+			Amount:      amount,
+			Reason:      "历史退款",
+			Status:      1,
+			SuccessAt:   successAt,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&refund).Error; err != nil {
+				return err
+			}
+			return tx.Model(&model.Payment{}).Where("id = ?", pay.ID).Updates(map[string]any{
+				"refund_amount": amount,
+				"refund_at":     successAt,
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("补登支付 %s 的退款流水失败: %w", pay.OrderNo, err)
+		}
+	}
+	if len(pays) > 0 {
+		log.Printf("已为 %d 笔历史退款补登退款流水", len(pays))
+	}
+	return nil
+}
+
+// BackfillPublishedAt 为记录发布时间前已发布的活动（含已删除的）补登发布时间，以创建时间为准
+// 公开日历据此为撤回发布或删除的活动输出取消事件。需在 AutoMigrate 之后执行，可重复执行
+func BackfillPublishedAt(db *gorm.DB) error {
+	res := db.Unscoped().Model(&model.Activity{}).
+		Where("status <> 0 AND published_at IS NULL").
+		Update("published_at", gorm.Expr("created_at"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("已为 %d 个历史活动补登发布时间", res.RowsAffected)
+	}
+	return nil
+}
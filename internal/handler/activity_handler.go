@@ -1,39 +1,81 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
 	"github.com/zzhtl/go-mountain/internal/pkg/response"
 	"github.com/zzhtl/go-mountain/internal/service"
 )
 
 // ActivityHandler 活动处理器
 type ActivityHandler struct {
-	svc *service.ActivityService
+	svc       *service.ActivityService
+	ticketSvc *service.ActivityTicketService
 }
 
 // NewActivityHandler 创建活动处理器
-func NewActivityHandler(svc *service.ActivityService) *ActivityHandler {
-	return &ActivityHandler{svc: svc}
+func NewActivityHandler(svc *service.ActivityService, ticketSvc *service.ActivityTicketService) *ActivityHandler {
+	return &ActivityHandler{svc: svc, ticketSvc: ticketSvc}
+}
+
+// geoRequest 结构化地理位置参数，经纬度需成对提供
+type geoRequest struct {
+	Latitude         *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude        *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Address          string   `json:"address"`
+	MeetingPoint     string   `json:"meeting_point"`
+	MeetingLatitude  *float64 `json:"meeting_latitude" binding:"required_with=MeetingLongitude,omitempty,min=-90,max=90"`
+	MeetingLongitude *float64 `json:"meeting_longitude" binding:"required_with=MeetingLatitude,omitempty,min=-180,max=180"`
+}
+
+func (r *geoRequest) toModel() model.GeoLocation {
+	return model.GeoLocation{
+		Latitude:         r.Latitude,
+		Longitude:        r.Longitude,
+		Address:          r.Address,
+		MeetingPoint:     r.MeetingPoint,
+		MeetingLatitude:  r.MeetingLatitude,
+		MeetingLongitude: r.MeetingLongitude,
+	}
+}
+
+// fillUpdates 将地理位置字段写入更新字段表
+func (r *geoRequest) fillUpdates(updates map[string]any) {
+	updates["latitude"] = r.Latitude
+	updates["longitude"] = r.Longitude
+	updates["address"] = r.Address
+	updates["meeting_point"] = r.MeetingPoint
+	updates["meeting_latitude"] = r.MeetingLatitude
+	updates["meeting_longitude"] = r.MeetingLongitude
 }
 
 type activityRequest struct {
-	Title           string     `json:"title" binding:"required"`
-	Description     string     `json:"description"`
-	Content         string     `json:"content"`
-	Thumbnail       string     `json:"thumbnail"`
-	Location        string     `json:"location"`
-	StartTime       time.Time  `json:"start_time" binding:"required"`
-	EndTime         time.Time  `json:"end_time" binding:"required"`
-	RegStartTime    *time.Time `json:"reg_start_time"`
-	RegEndTime      *time.Time `json:"reg_end_time"`
-	MaxParticipants int        `json:"max_participants"`
-	Price           float64    `json:"price"`
-	Status          int        `json:"status"`
+	geoRequest
+	Title             string          `json:"title" binding:"required"`
+	Description       string          `json:"description"`
+	Content           string          `json:"content"`
+	Thumbnail         string          `json:"thumbnail"`
+	Location          string          `json:"location"`
+	StartTime         time.Time       `json:"start_time" binding:"required"`
+	EndTime           time.Time       `json:"end_time" binding:"required"`
+	RegStartTime      *time.Time      `json:"reg_start_time"`
+	RegEndTime        *time.Time      `json:"reg_end_time"`
+	MaxParticipants   int             `json:"max_participants"`
+	Price             money.Money     `json:"price" binding:"min=0"`
+	RequiresApproval  bool            `json:"requires_approval"`
+	RefundFullDays    int             `json:"refund_full_days" binding:"min=0"`
+	RefundPartialDays int             `json:"refund_partial_days" binding:"min=0,ltefield=RefundFullDays"`
+	RefundPartialRate int             `json:"refund_partial_rate" binding:"min=0,max=100"`
+	FormSchema        json.RawMessage `json:"form_schema"`
+	Status            int             `json:"status"`
 }
 
 // List 获取活动列表（后台）
@@ -79,19 +121,25 @@ func (h *ActivityHandler) Create(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	activity := &model.Activity{
-		Title:           req.Title,
-		Description:     req.Description,
-		Content:         req.Content,
-		Thumbnail:       req.Thumbnail,
-		Location:        req.Location,
-		StartTime:       req.StartTime,
-		EndTime:         req.EndTime,
-		RegStartTime:    req.RegStartTime,
-		RegEndTime:      req.RegEndTime,
-		MaxParticipants: req.MaxParticipants,
-		Price:           req.Price,
-		Status:          req.Status,
-		CreatedBy:       int64(userID.(float64)),
+		GeoLocation:       req.toModel(),
+		Title:             req.Title,
+		Description:       req.Description,
+		Content:           req.Content,
+		Thumbnail:         req.Thumbnail,
+		Location:          req.Location,
+		StartTime:         req.StartTime,
+		EndTime:           req.EndTime,
+		RegStartTime:      req.RegStartTime,
+		RegEndTime:        req.RegEndTime,
+		MaxParticipants:   req.MaxParticipants,
+		Price:             req.Price,
+		RequiresApproval:  req.RequiresApproval,
+		RefundFullDays:    req.RefundFullDays,
+		RefundPartialDays: req.RefundPartialDays,
+		RefundPartialRate: req.RefundPartialRate,
+		FormSchema:        req.FormSchema,
+		Status:            req.Status,
+		CreatedBy:         int64(userID.(float64)),
 	}
 
 	if err := h.svc.Create(c.Request.Context(), activity); err != nil {
@@ -117,18 +165,35 @@ func (h *ActivityHandler) Update(c *gin.Context) {
 	}
 
 	updates := map[string]any{
-		"title":            req.Title,
-		"description":      req.Description,
-		"content":          req.Content,
-		"thumbnail":        req.Thumbnail,
-		"location":         req.Location,
-		"start_time":       req.StartTime,
-		"end_time":         req.EndTime,
-		"reg_start_time":   req.RegStartTime,
-		"reg_end_time":     req.RegEndTime,
-		"max_participants": req.MaxParticipants,
-		"price":            req.Price,
-		"status":           req.Status,
+		"title":               req.Title,
+		"description":         req.Description,
+		"content":             req.Content,
+		"thumbnail":           req.Thumbnail,
+		"location":            req.Location,
+		"start_time":          req.StartTime,
+		"end_time":            req.EndTime,
+		"reg_start_time":      req.RegStartTime,
+		"reg_end_time":        req.RegEndTime,
+		"max_participants":    req.MaxParticipants,
+		"price":               req.Price,
+		"requires_approval":   req.RequiresApproval,
+		"refund_full_days":    req.RefundFullDays,
+		"refund_partial_days": req.RefundPartialDays,
+		"refund_partial_rate": req.RefundPartialRate,
+		"form_schema":         req.FormSchema,
+		"status":              req.Status,
+	}
+	req.fillUpdates(updates)
+
+	// scope=future 时同步修改周期活动的本期及之后各期
+	if c.Query("scope") == "future" {
+		count, err := h.svc.UpdateFuture(c.Request.Context(), id, updates)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.OK(c, gin.H{"id": id, "updated": count})
+		return
 	}
 
 	if err := h.svc.Update(c.Request.Context(), id, updates); err != nil {
@@ -139,6 +204,32 @@ func (h *ActivityHandler) Update(c *gin.Context) {
 	response.OK(c, gin.H{"id": id})
 }
 
+// Clone 复制活动（内容、票种和报名表单）
+func (h *ActivityHandler) Clone(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	// 请求体可为空，表示按原活动原样复制
+	var req service.CloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	activity, err := h.svc.Clone(c.Request.Context(), id, operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, activity)
+}
+
 // UpdateStatus 更新活动状态
 func (h *ActivityHandler) UpdateStatus(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -179,6 +270,52 @@ func (h *ActivityHandler) Delete(c *gin.Context) {
 	response.NoContent(c)
 }
 
+// ListTickets 获取活动票种列表
+func (h *ActivityHandler) ListTickets(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	list, err := h.ticketSvc.ListByActivity(c.Request.Context(), id, false)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, list)
+}
+
+// SaveTickets 保存活动票种（全量覆盖）
+func (h *ActivityHandler) SaveTickets(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Tickets []service.TicketRequest `json:"tickets" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if _, err := h.svc.Get(c.Request.Context(), id); err != nil {
+		response.NotFound(c, "活动不存在")
+		return
+	}
+
+	if err := h.ticketSvc.Save(c.Request.Context(), id, req.Tickets); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
 // ListForMP 获取活动列表（小程序）
 func (h *ActivityHandler) ListForMP(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -193,6 +330,29 @@ func (h *ActivityHandler) ListForMP(c *gin.Context) {
 	response.PageOK(c, list, total, page, pageSize)
 }
 
+// Nearby 获取附近活动（小程序）
+// 参数: lat、lng 为用户位置（GCJ-02），radius 为搜索半径（千米，默认 10，最大 200），limit 默认 20，最大 100
+func (h *ActivityHandler) Nearby(c *gin.Context) {
+	var req struct {
+		Lat    *float64 `form:"lat" binding:"required,min=-90,max=90"`
+		Lng    *float64 `form:"lng" binding:"required,min=-180,max=180"`
+		Radius float64  `form:"radius,default=10" binding:"gt=0,max=200"`
+		Limit  int      `form:"limit,default=20" binding:"min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	list, err := h.svc.Nearby(c.Request.Context(), *req.Lat, *req.Lng, req.Radius, req.Limit)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, list)
+}
+
 // GetForMP 获取活动详情（小程序）
 func (h *ActivityHandler) GetForMP(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// ActivityReviewHandler 活动评价处理器
+type ActivityReviewHandler struct {
+	svc *service.ActivityReviewService
+}
+
+// NewActivityReviewHandler 创建活动评价处理器
+func NewActivityReviewHandler(svc *service.ActivityReviewService) *ActivityReviewHandler {
+	return &ActivityReviewHandler{svc: svc}
+}
+
+// Create 提交活动评价（小程序端）
+func (h *ActivityReviewHandler) Create(c *gin.Context) {
+	var req service.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	review, err := h.svc.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, review)
+}
+
+// ListForMP 获取活动的公开评价（小程序端）
+func (h *ActivityReviewHandler) ListForMP(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	list, total, err := h.svc.ListForMP(c.Request.Context(), id, page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// List 获取评价列表（后台）
+func (h *ActivityReviewHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	activityID, _ := strconv.ParseInt(c.Query("activity_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize, activityID, status)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// UpdateStatus 显示或隐藏评价（后台）
+func (h *ActivityReviewHandler) UpdateStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Status int `json:"status" binding:"oneof=0 1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.UpdateStatus(c.Request.Context(), id, req.Status); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// SetFeatured 设置或取消精选评价（后台）
+func (h *ActivityReviewHandler) SetFeatured(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Featured bool `json:"featured"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.SetFeatured(c.Request.Context(), id, req.Featured); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
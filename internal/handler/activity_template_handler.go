@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// ActivityTemplateHandler 活动模板处理器
+type ActivityTemplateHandler struct {
+	svc *service.ActivityTemplateService
+}
+
+// NewActivityTemplateHandler 创建活动模板处理器
+func NewActivityTemplateHandler(svc *service.ActivityTemplateService) *ActivityTemplateHandler {
+	return &ActivityTemplateHandler{svc: svc}
+}
+
+type activityTemplateRequest struct {
+	geoRequest
+	Name              string                   `json:"name" binding:"required"`
+	Title             string                   `json:"title" binding:"required"`
+	Description       string                   `json:"description"`
+	Content           string                   `json:"content"`
+	Thumbnail         string                   `json:"thumbnail"`
+	Location          string                   `json:"location"`
+	DurationMinutes   int                      `json:"duration_minutes" binding:"min=0"`
+	MaxParticipants   int                      `json:"max_participants"`
+	Price             money.Money              `json:"price" binding:"min=0"`
+	RequiresApproval  bool                     `json:"requires_approval"`
+	RefundFullDays    int                      `json:"refund_full_days" binding:"min=0"`
+	RefundPartialDays int                      `json:"refund_partial_days" binding:"min=0,ltefield=RefundFullDays"`
+	RefundPartialRate int                      `json:"refund_partial_rate" binding:"min=0,max=100"`
+	FormSchema        json.RawMessage          `json:"form_schema"`
+	Tickets           []service.TemplateTicket `json:"tickets" binding:"dive"`
+}
+
+// List 获取活动模板列表
+func (h *ActivityTemplateHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Get 获取活动模板详情
+func (h *ActivityTemplateHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	tpl, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.OK(c, tpl)
+}
+
+// Create 创建活动模板
+func (h *ActivityTemplateHandler) Create(c *gin.Context) {
+	var req activityTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	tickets, _ := json.Marshal(req.Tickets)
+
+	tpl := &model.ActivityTemplate{
+		GeoLocation:       req.toModel(),
+		Name:              req.Name,
+		Title:             req.Title,
+		Description:       req.Description,
+		Content:           req.Content,
+		Thumbnail:         req.Thumbnail,
+		Location:          req.Location,
+		DurationMinutes:   req.DurationMinutes,
+		MaxParticipants:   req.MaxParticipants,
+		Price:             req.Price,
+		RequiresApproval:  req.RequiresApproval,
+		RefundFullDays:    req.RefundFullDays,
+		RefundPartialDays: req.RefundPartialDays,
+		RefundPartialRate: req.RefundPartialRate,
+		FormSchema:        req.FormSchema,
+		Tickets:           tickets,
+		CreatedBy:         int64(c.GetFloat64("user_id")),
+	}
+
+	if err := h.svc.Create(c.Request.Context(), tpl); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Created(c, tpl)
+}
+
+// Update 更新活动模板
+func (h *ActivityTemplateHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req activityTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	tickets, _ := json.Marshal(req.Tickets)
+
+	updates := map[string]any{
+		"name":                req.Name,
+		"title":               req.Title,
+		"description":         req.Description,
+		"content":             req.Content,
+		"thumbnail":           req.Thumbnail,
+		"location":            req.Location,
+		"duration_minutes":    req.DurationMinutes,
+		"max_participants":    req.MaxParticipants,
+		"price":               req.Price,
+		"requires_approval":   req.RequiresApproval,
+		"refund_full_days":    req.RefundFullDays,
+		"refund_partial_days": req.RefundPartialDays,
+		"refund_partial_rate": req.RefundPartialRate,
+		"form_schema":         req.FormSchema,
+		"tickets":             json.RawMessage(tickets),
+	}
+	req.fillUpdates(updates)
+
+	if err := h.svc.Update(c.Request.Context(), id, updates); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// Delete 删除活动模板
+func (h *ActivityTemplateHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// Generate 按重复规则生成周期活动
+func (h *ActivityTemplateHandler) Generate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.GenerateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	series, activities, err := h.svc.Generate(c.Request.Context(), id, operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, gin.H{"series": series, "activities": activities})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/ical"
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// CalendarHandler 日历订阅处理器
+type CalendarHandler struct {
+	svc *service.CalendarService
+}
+
+// NewCalendarHandler 创建日历订阅处理器
+func NewCalendarHandler(svc *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{svc: svc}
+}
+
+// writeCalendar 输出 text/calendar 响应
+func writeCalendar(c *gin.Context, cal *ical.Calendar, filename string) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	cal.WriteTo(c.Writer)
+}
+
+// PublicFeed 公开活动日历订阅（无需认证）
+func (h *CalendarHandler) PublicFeed(c *gin.Context) {
+	cal, err := h.svc.PublicFeed(c.Request.Context())
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	writeCalendar(c, cal, "activities.ics")
+}
+
+// UserFeed 个人报名日历订阅（凭订阅令牌访问，日历客户端无法携带 JWT）
+func (h *CalendarHandler) UserFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	cal, err := h.svc.UserFeed(c.Request.Context(), token)
+	if err != nil {
+		response.NotFound(c, "订阅地址无效")
+		return
+	}
+
+	writeCalendar(c, cal, "my-registrations.ics")
+}
+
+// GetToken 获取个人日历订阅地址（小程序端）
+func (h *CalendarHandler) GetToken(c *gin.Context) {
+	h.token(c, false)
+}
+
+// ResetToken 重置个人日历订阅地址，旧地址立即失效（小程序端）
+func (h *CalendarHandler) ResetToken(c *gin.Context) {
+	h.token(c, true)
+}
+
+func (h *CalendarHandler) token(c *gin.Context, reset bool) {
+	userID := int64(c.GetFloat64("user_id"))
+
+	token, err := h.svc.UserToken(c.Request.Context(), userID, reset)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	response.OK(c, gin.H{
+		"token": token,
+		"url":   scheme + "://" + c.Request.Host + "/api/mp/calendar/feeds/" + token + ".ics",
+	})
+}
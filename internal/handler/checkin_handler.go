@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// CheckinHandler 活动签到处理器
+type CheckinHandler struct {
+	svc *service.CheckinService
+}
+
+// NewCheckinHandler 创建活动签到处理器
+func NewCheckinHandler(svc *service.CheckinService) *CheckinHandler {
+	return &CheckinHandler{svc: svc}
+}
+
+// GetToken 获取报名签到码（小程序端渲染为二维码）
+func (h *CheckinHandler) GetToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	token, err := h.svc.GetToken(c.Request.Context(), userID, id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"registration_id": id, "token": token})
+}
+
+// Scan 扫码签到（后台/组织者）
+func (h *CheckinHandler) Scan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	reg, err := h.svc.Scan(c.Request.Context(), id, req.Token, operatorID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, reg)
+}
+
+// Report 获取活动签到报表（后台）
+func (h *CheckinHandler) Report(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	report, err := h.svc.Report(c.Request.Context(), id)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, report)
+}
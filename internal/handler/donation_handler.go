@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// DonationHandler 募捐处理器
+type DonationHandler struct {
+	svc *service.DonationService
+}
+
+// NewDonationHandler 创建募捐处理器
+func NewDonationHandler(svc *service.DonationService) *DonationHandler {
+	return &DonationHandler{svc: svc}
+}
+
+type donationCampaignRequest struct {
+	Title         string        `json:"title" binding:"required"`
+	Description   string        `json:"description"`
+	Content       string        `json:"content"`
+	Cover         string        `json:"cover"`
+	GoalAmount    money.Money   `json:"goal_amount" binding:"min=0"`
+	PresetAmounts []money.Money `json:"preset_amounts" binding:"max=10,dive,gt=0,max=100000000"` // 单项上限 100 万元（分）
+	StartTime     *time.Time    `json:"start_time"`
+	EndTime       *time.Time    `json:"end_time"`
+	Status        int           `json:"status" binding:"oneof=0 1 2"`
+}
+
+// presetAmounts 预设金额序列化为 JSON，未设置时为空
+func (r *donationCampaignRequest) presetAmounts() json.RawMessage {
+	if len(r.PresetAmounts) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(r.PresetAmounts)
+	return data
+}
+
+// List 获取募捐项目列表（后台）
+func (h *DonationHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize, status)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Get 获取募捐项目详情（后台）
+func (h *DonationHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	campaign, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.OK(c, campaign)
+}
+
+// Create 创建募捐项目
+func (h *DonationHandler) Create(c *gin.Context) {
+	var req donationCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	campaign := &model.DonationCampaign{
+		Title:         req.Title,
+		Description:   req.Description,
+		Content:       req.Content,
+		Cover:         req.Cover,
+		GoalAmount:    req.GoalAmount,
+		PresetAmounts: req.presetAmounts(),
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		Status:        req.Status,
+		CreatedBy:     int64(c.GetFloat64("user_id")),
+	}
+
+	if err := h.svc.Create(c.Request.Context(), campaign); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Created(c, campaign)
+}
+
+// Update 更新募捐项目
+func (h *DonationHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req donationCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updates := map[string]any{
+		"title":          req.Title,
+		"description":    req.Description,
+		"content":        req.Content,
+		"cover":          req.Cover,
+		"goal_amount":    req.GoalAmount,
+		"preset_amounts": req.presetAmounts(),
+		"start_time":     req.StartTime,
+		"end_time":       req.EndTime,
+		"status":         req.Status,
+	}
+
+	if err := h.svc.Update(c.Request.Context(), id, updates); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// Delete 删除募捐项目
+func (h *DonationHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// UpdateStatus 更新募捐项目状态
+func (h *DonationHandler) UpdateStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Status int `json:"status" binding:"oneof=0 1 2"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.UpdateStatus(c.Request.Context(), id, req.Status); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// ListDonations 获取捐赠记录（后台）
+func (h *DonationHandler) ListDonations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	campaignID, _ := strconv.ParseInt(c.Query("campaign_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	list, total, err := h.svc.ListDonations(c.Request.Context(), page, pageSize, campaignID, status)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// ListForMP 获取募捐项目列表（小程序端）
+func (h *DonationHandler) ListForMP(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	list, total, err := h.svc.ListForMP(c.Request.Context(), page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// GetForMP 获取募捐项目详情（小程序端）
+func (h *DonationHandler) GetForMP(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	campaign, err := h.svc.GetForMP(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.OK(c, campaign)
+}
+
+// Donate 发起捐赠并返回拉起微信支付所需参数（小程序端及网站）
+func (h *DonationHandler) Donate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.DonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.ClientIP = c.ClientIP()
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	result, err := h.svc.Donate(c.Request.Context(), userID, id, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// MyDonations 我的捐赠记录（小程序端）
+func (h *DonationHandler) MyDonations(c *gin.Context) {
+	userID := int64(c.GetFloat64("user_id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	list, total, err := h.svc.GetByUser(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Donors 募捐项目捐赠墙（公开）
+func (h *DonationHandler) Donors(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	list, total, err := h.svc.Donors(c.Request.Context(), id, page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// writeReceipt 输出收据 PDF
+func (h *DonationHandler) writeReceipt(c *gin.Context, receipt *model.DonationReceipt) {
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", `attachment; filename="donation-receipt-`+receipt.SerialNo+`.pdf"`)
+	c.Status(http.StatusOK)
+	h.svc.WriteReceiptPDF(c.Request.Context(), c.Writer, receipt)
+}
+
+// MyReceipt 下载本人捐赠的收据 PDF（小程序端）
+func (h *DonationHandler) MyReceipt(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	receipt, err := h.svc.GetReceiptForUser(c.Request.Context(), userID, id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	h.writeReceipt(c, receipt)
+}
+
+// ListReceipts 获取收据列表（后台）
+func (h *DonationHandler) ListReceipts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	campaignID, _ := strconv.ParseInt(c.Query("campaign_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	list, total, err := h.svc.ListReceipts(c.Request.Context(), page, pageSize, campaignID, status)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// ReceiptPDF 下载收据 PDF（后台）
+func (h *DonationHandler) ReceiptPDF(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	receipt, err := h.svc.GetReceipt(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	h.writeReceipt(c, receipt)
+}
+
+// VoidReceipt 作废收据（后台）
+func (h *DonationHandler) VoidReceipt(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.VoidReceipt(c.Request.Context(), id, req.Reason); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
@@ -1,13 +1,15 @@
 package handler
 
 import (
-	"io"
+	"errors"
 	"strconv"
 
-	"github.com/ArtisanCloud/PowerWeChat/v3/src/kernel/models"
-	"github.com/ArtisanCloud/PowerWeChat/v3/src/payment/notify/request"
 	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
 
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/payprovider"
 	"github.com/zzhtl/go-mountain/internal/pkg/response"
 	"github.com/zzhtl/go-mountain/internal/service"
 )
@@ -28,8 +30,9 @@ func (h *PaymentHandler) List(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
 	bizType := c.Query("biz_type")
+	abnormal := c.Query("abnormal") == "1"
 
-	list, total, err := h.svc.List(c.Request.Context(), page, pageSize, status, bizType)
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize, status, bizType, abnormal)
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -55,7 +58,7 @@ func (h *PaymentHandler) Get(c *gin.Context) {
 	response.OK(c, payment)
 }
 
-// Refund 退款（后台操作）
+// Refund 退款（后台操作），可指定金额部分退款，未指定时退还剩余可退金额
 func (h *PaymentHandler) Refund(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -63,108 +66,175 @@ func (h *PaymentHandler) Refund(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.RefundOrder(c.Request.Context(), id); err != nil {
+	var req service.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
 
-	response.OK(c, gin.H{"id": id})
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	refund, err := h.svc.RefundOrder(c.Request.Context(), id, operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, refund)
 }
 
-// WechatNotify 微信支付回调（不需要JWT认证）
-// 使用 PowerWeChat 进行签名验证和数据解密
-func (h *PaymentHandler) WechatNotify(c *gin.Context) {
-	ctx := c.Request.Context()
+// ListRefunds 获取退款流水列表（后台）
+func (h *PaymentHandler) ListRefunds(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	paymentID, _ := strconv.ParseInt(c.Query("payment_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
 
-	// 获取 PowerWeChat 支付实例
-	app, err := h.svc.GetPaymentApp(ctx)
+	list, total, err := h.svc.ListRefunds(c.Request.Context(), page, pageSize, paymentID, status)
 	if err != nil {
-		c.JSON(500, gin.H{"code": "FAIL", "message": "支付配置错误: " + err.Error()})
+		response.ServerError(c, err.Error())
 		return
 	}
 
-	// 使用 PowerWeChat 处理支付回调（自动验签 + 解密通知数据）
-	resp, err := app.HandlePaidNotify(
-		c.Request,
-		func(message *request.RequestNotify, transaction *models.Transaction, fail func(message string)) interface{} {
-			if transaction == nil {
-				fail("交易数据为空")
-				return nil
-			}
+	response.PageOK(c, list, total, page, pageSize)
+}
 
-			// 仅处理支付成功的通知
-			if transaction.TradeState != "SUCCESS" {
-				return true
-			}
+// WechatNotify 微信支付回调（不需要JWT认证）
+// 由支付渠道使用 PowerWeChat 进行签名验证和数据解密
+func (h *PaymentHandler) WechatNotify(c *gin.Context) {
+	notifyResult(c, h.svc.HandlePaidNotify(c.Request.Context(), payprovider.ProviderWechat, c.Request))
+}
 
-			// 序列化通知数据用于存档
-			notifyData := service.MarshalTransaction(transaction)
+// WechatRefundNotify 微信退款结果回调（不需要JWT认证）
+func (h *PaymentHandler) WechatRefundNotify(c *gin.Context) {
+	notifyResult(c, h.svc.HandleRefundNotify(c.Request.Context(), payprovider.ProviderWechat, c.Request))
+}
 
-			// 更新支付状态和关联业务状态
-			if err := h.svc.HandleNotify(ctx, transaction.OutTradeNo, transaction.TransactionID, notifyData); err != nil {
-				fail("处理支付通知失败: " + err.Error())
-				return nil
-			}
+// MockNotify 模拟支付结果回调，仅在启用模拟支付时可用
+func (h *PaymentHandler) MockNotify(c *gin.Context) {
+	notifyResult(c, h.svc.HandlePaidNotify(c.Request.Context(), payprovider.ProviderMock, c.Request))
+}
 
-			return true
-		},
-	)
+// MockRefundNotify 模拟退款结果回调，仅在启用模拟支付时可用
+func (h *PaymentHandler) MockRefundNotify(c *gin.Context) {
+	notifyResult(c, h.svc.HandleRefundNotify(c.Request.Context(), payprovider.ProviderMock, c.Request))
+}
 
+// notifyResult 按微信支付 V3 回调应答格式返回处理结果，失败时渠道会重试通知
+func notifyResult(c *gin.Context, err error) {
 	if err != nil {
 		c.JSON(500, gin.H{"code": "FAIL", "message": err.Error()})
 		return
 	}
-
-	// 将 PowerWeChat 的响应转发给微信服务器
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
+	c.JSON(200, gin.H{"code": "SUCCESS", "message": "成功"})
 }
 
-// CreateOrder 创建支付订单（小程序端）
+// CreateOrder 创建支付订单（小程序端及网站）
+// 支持单人报名（registration_id）和团体报名订单（order_id），重复提交时复用未过期的待支付订单；
+// trade_type 为下单场景：jsapi（小程序，默认）、native（网站扫码）、h5（手机浏览器）
 func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 	var req struct {
-		RegistrationID int64 `json:"registration_id" binding:"required"`
+		RegistrationID int64  `json:"registration_id"`
+		OrderID        int64  `json:"order_id"`
+		TradeType      string `json:"trade_type" binding:"omitempty,oneof=jsapi native h5"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	if req.RegistrationID == 0 && req.OrderID == 0 {
+		response.BadRequest(c, "缺少报名记录或报名订单")
+		return
+	}
 
 	userID := int64(c.GetFloat64("user_id"))
 	openID, _ := c.Get("openid")
 
-	// 查询报名记录获取金额信息
-	regSvc := service.NewRegistrationService(h.svc.GetDB())
-	reg, err := regSvc.Get(c.Request.Context(), req.RegistrationID)
-	if err != nil {
-		response.NotFound(c, "报名记录不存在")
-		return
-	}
+	var (
+		amount           money.Money
+		bizType          string
+		bizID            int64
+		activityID       int64
+		useActivityPrice bool
+	)
 
-	if reg.UserID != userID {
-		response.Forbidden(c, "无权操作")
-		return
-	}
-
-	if reg.Status != 0 {
-		response.BadRequest(c, "该报名记录状态不支持支付")
-		return
+	if req.OrderID > 0 {
+		// 查询团体报名订单获取合计金额
+		orderSvc := service.NewRegistrationOrderService(h.svc.GetDB(), h.svc)
+		order, err := orderSvc.Get(c.Request.Context(), req.OrderID)
+		if err != nil {
+			response.NotFound(c, "报名订单不存在")
+			return
+		}
+
+		if order.UserID != userID {
+			response.Forbidden(c, "无权操作")
+			return
+		}
+
+		if err := orderSvc.CheckPayable(order); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		amount, bizType, bizID, activityID = order.TotalAmount, "registration_order", order.ID, order.ActivityID
+	} else {
+		// 查询报名记录获取金额信息
+		regSvc := service.NewRegistrationService(h.svc.GetDB(), h.svc)
+		reg, err := regSvc.Get(c.Request.Context(), req.RegistrationID)
+		if err != nil {
+			response.NotFound(c, "报名记录不存在")
+			return
+		}
+
+		if reg.UserID != userID {
+			response.Forbidden(c, "无权操作")
+			return
+		}
+
+		if reg.OrderID != nil {
+			response.BadRequest(c, "该报名属于团体订单，请按订单支付")
+			return
+		}
+
+		if err := service.CheckRegistrationPayable(reg.Status); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		amount, bizType, bizID, activityID = reg.Amount, "registration", reg.ID, reg.ActivityID
+
+		// 兼容票种上线前的报名记录（未锁定金额时按活动价格收费）
+		useActivityPrice = reg.TicketID == nil && reg.Amount == 0
 	}
 
 	// 获取活动信息
 	actSvc := service.NewActivityService(h.svc.GetDB())
-	activity, err := actSvc.Get(c.Request.Context(), reg.ActivityID)
+	activity, err := actSvc.Get(c.Request.Context(), activityID)
 	if err != nil {
 		response.NotFound(c, "活动不存在")
 		return
 	}
+	if useActivityPrice {
+		amount = activity.Price
+	}
 
 	openIDStr, _ := openID.(string)
-	payment, payParams, err := h.svc.CreatePrepayOrder(
-		c.Request.Context(), userID, activity.Price,
-		"registration", req.RegistrationID, openIDStr, activity.Title,
-	)
+	payment, payParams, err := h.svc.CreatePrepayOrder(c.Request.Context(), &service.PrepayOrder{
+		UserID:      userID,
+		Amount:      amount,
+		BizType:     bizType,
+		BizID:       bizID,
+		Description: activity.Title,
+		TradeType:   req.TradeType,
+		OpenID:      openIDStr,
+		ClientIP:    c.ClientIP(),
+	})
 	if err != nil {
+		if errors.Is(err, errcode.ErrPaymentAlreadyPaid) || errors.Is(err, errcode.ErrPaymentPaying) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -176,6 +246,7 @@ func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 }
 
 // QueryOrder 查询支付状态（小程序端轮询）
+// 仅限本人的订单；待支付的订单会主动向支付渠道查询并同步，查询失败时返回本地状态
 func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 	orderNo := c.Query("order_no")
 	if orderNo == "" {
@@ -188,6 +259,46 @@ func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 		response.NotFound(c, "支付记录不存在")
 		return
 	}
+	if payment.UserID != int64(c.GetFloat64("user_id")) {
+		response.Forbidden(c, "无权操作")
+		return
+	}
+
+	if synced, _ := h.svc.SyncOrder(c.Request.Context(), orderNo); synced != nil {
+		payment = synced
+	}
 
 	response.OK(c, payment)
 }
+
+// QRCode 获取 Native 支付二维码图片（网站扫码支付）
+// 仅限本人待支付的扫码订单，二维码内容为下单返回的 code_url
+func (h *PaymentHandler) QRCode(c *gin.Context) {
+	orderNo := c.Query("order_no")
+	if orderNo == "" {
+		response.BadRequest(c, "缺少订单号")
+		return
+	}
+
+	payment, err := h.svc.GetByOrderNo(c.Request.Context(), orderNo)
+	if err != nil {
+		response.NotFound(c, "支付记录不存在")
+		return
+	}
+	if payment.UserID != int64(c.GetFloat64("user_id")) {
+		response.Forbidden(c, "无权操作")
+		return
+	}
+	if payment.PayType != payprovider.PayTypeWechatNative || payment.Status != 0 {
+		response.BadRequest(c, "该订单不是待支付的扫码支付订单")
+		return
+	}
+
+	png, err := qrcode.Encode(payment.PrepayID, qrcode.Medium, 256)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, "image/png", png)
+}
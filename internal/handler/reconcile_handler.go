@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// ReconcileHandler 支付对账处理器
+type ReconcileHandler struct {
+	svc *service.ReconcileService
+}
+
+// NewReconcileHandler 创建支付对账处理器
+func NewReconcileHandler(svc *service.ReconcileService) *ReconcileHandler {
+	return &ReconcileHandler{svc: svc}
+}
+
+// List 获取对账报告列表
+func (h *ReconcileHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Get 获取对账报告详情（含差异明细）
+func (h *ReconcileHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	report, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.OK(c, report)
+}
+
+// Run 手动对指定日期的账单（重新）对账
+func (h *ReconcileHandler) Run(c *gin.Context) {
+	var req struct {
+		BillDate string `json:"bill_date" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	report, err := h.svc.Reconcile(c.Request.Context(), req.BillDate)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, report)
+}
@@ -1,25 +1,39 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/zzhtl/go-mountain/internal/middleware"
+	"github.com/zzhtl/go-mountain/internal/model"
 	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/pkg/xlsx"
 	"github.com/zzhtl/go-mountain/internal/service"
 )
 
+// sensitivePermission 查看未脱敏手机号、证件号所需的权限标识
+const sensitivePermission = "registration:view_sensitive"
+
 // RegistrationHandler 报名处理器
 type RegistrationHandler struct {
-	svc *service.RegistrationService
+	svc    *service.RegistrationService
+	logSvc *service.OperationLogService
 }
 
 // NewRegistrationHandler 创建报名处理器
-func NewRegistrationHandler(svc *service.RegistrationService) *RegistrationHandler {
-	return &RegistrationHandler{svc: svc}
+func NewRegistrationHandler(svc *service.RegistrationService, logSvc *service.OperationLogService) *RegistrationHandler {
+	return &RegistrationHandler{svc: svc, logSvc: logSvc}
 }
 
-// List 获取报名列表（后台）
+// List 获取报名列表（后台），无查看敏感信息权限时手机号和证件号脱敏
 func (h *RegistrationHandler) List(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
@@ -31,11 +45,237 @@ func (h *RegistrationHandler) List(c *gin.Context) {
 		response.ServerError(c, err.Error())
 		return
 	}
+	if !middleware.HasPermission(c, h.svc.GetDB(), sensitivePermission) {
+		for i := range list {
+			list[i].MaskSensitive()
+		}
+	}
 
 	response.PageOK(c, list, total, page, pageSize)
 }
 
-// Get 获取报名详情（后台）
+// record 记录后台报名操作日志
+func (h *RegistrationHandler) record(c *gin.Context, action, targetType string, targetID int64, detail any) {
+	// 客户端中断（如导出中途断开）时请求上下文已取消，日志仍需写入
+	ctx := context.WithoutCancel(c.Request.Context())
+	err := h.logSvc.Record(ctx, &model.OperationLog{
+		UserID:     int64(c.GetFloat64("user_id")),
+		Username:   c.GetString("username"),
+		Module:     "registration",
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}, detail)
+	if err != nil {
+		log.Printf("记录操作日志失败（%s %s %d）: %v", action, targetType, targetID, err)
+	}
+}
+
+// AdminCreate 后台代报名（线下报名录入）
+func (h *RegistrationHandler) AdminCreate(c *gin.Context) {
+	var req service.AdminCreateRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	reg, err := h.svc.AdminCreate(c.Request.Context(), operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.record(c, "create", "registration", reg.ID, gin.H{
+		"activity_id": reg.ActivityID,
+		"user_id":     reg.UserID,
+		"paid":        req.Paid,
+	})
+	response.Created(c, reg)
+}
+
+// Update 修改报名人信息（后台）
+func (h *RegistrationHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.UpdateRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.svc.AdminUpdate(c.Request.Context(), id, &req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.record(c, "update", "registration", id, nil)
+	response.OK(c, gin.H{"id": id})
+}
+
+// AdminCancel 代为取消报名（后台）
+func (h *RegistrationHandler) AdminCancel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		FullRefund bool `json:"full_refund"` // 忽略退款规则全额退款
+	}
+	c.ShouldBindJSON(&req)
+
+	if err := h.svc.AdminCancel(c.Request.Context(), id, req.FullRefund); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.record(c, "cancel", "registration", id, gin.H{"full_refund": req.FullRefund})
+	response.OK(c, gin.H{"id": id})
+}
+
+// MarkPaid 标记报名已线下收款（后台）
+func (h *RegistrationHandler) MarkPaid(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.svc.MarkPaidOffline(c.Request.Context(), id); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.record(c, "mark_paid", "registration", id, nil)
+	response.OK(c, gin.H{"id": id})
+}
+
+// ExportColumns 获取可导出的列（后台）
+func (h *RegistrationHandler) ExportColumns(c *gin.Context) {
+	activityID, _ := strconv.ParseInt(c.Query("activity_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	columns, err := h.svc.ExportColumns(c.Request.Context(), &service.RegistrationExportOptions{
+		ActivityID: activityID,
+		Status:     status,
+	})
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, columns)
+}
+
+// Export 流式导出报名名单（后台）
+// 参数: activity_id、status、format(csv/xlsx)、columns(逗号分隔的列 key，为空导出全部)
+func (h *RegistrationHandler) Export(c *gin.Context) {
+	activityID, _ := strconv.ParseInt(c.Query("activity_id"), 10, 64)
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		response.BadRequest(c, "不支持的导出格式")
+		return
+	}
+
+	var columns []string
+	if v := strings.TrimSpace(c.Query("columns")); v != "" {
+		columns = strings.Split(v, ",")
+	}
+
+	opts := &service.RegistrationExportOptions{
+		ActivityID: activityID,
+		Status:     status,
+		Columns:    columns,
+		Masked:     !middleware.HasPermission(c, h.svc.GetDB(), sensitivePermission),
+	}
+
+	// 响应头在写出表头时才设置，列校验等错误仍可按 JSON 返回
+	filename := fmt.Sprintf("registrations_%s.%s", time.Now().Format("20060102150405"), format)
+	contentType := "text/csv; charset=utf-8"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	}
+
+	var (
+		count int
+		err   error
+	)
+	switch format {
+	case "xlsx":
+		var w *xlsx.Writer
+		count, err = h.svc.Export(c.Request.Context(), opts, func(row []string) error {
+			if w == nil {
+				start()
+				var err error
+				if w, err = xlsx.NewWriter(c.Writer, "报名名单"); err != nil {
+					return err
+				}
+			}
+			return w.WriteRow(row)
+		})
+		if err == nil {
+			err = w.Close()
+		}
+	default:
+		w := csv.NewWriter(c.Writer)
+		count, err = h.svc.Export(c.Request.Context(), opts, func(row []string) error {
+			if !started {
+				start()
+				c.Writer.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，便于 Excel 正确识别中文
+			}
+			return w.Write(csvSafeRow(row))
+		})
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+	}
+
+	if err != nil && !started {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// 已开始输出文件后无论成功与否都记录审计日志，导出中途失败时记录失败原因和已导出的行数
+	detail := gin.H{
+		"activity_id": activityID,
+		"status":      status,
+		"format":      format,
+		"columns":     columns,
+		"masked":      opts.Masked,
+		"rows":        count,
+	}
+	if err != nil {
+		detail["error"] = err.Error()
+	}
+	h.record(c, "export", "activity", activityID, detail)
+
+	if err != nil {
+		// 已开始输出文件时无法再返回错误响应，只能中断
+		c.Error(err)
+	}
+}
+
+// Get 获取报名详情（后台），无查看敏感信息权限时手机号和证件号脱敏
 func (h *RegistrationHandler) Get(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -48,10 +288,57 @@ func (h *RegistrationHandler) Get(c *gin.Context) {
 		response.NotFound(c, "报名记录不存在")
 		return
 	}
+	if !middleware.HasPermission(c, h.svc.GetDB(), sensitivePermission) {
+		item.MaskSensitive()
+	}
 
 	response.OK(c, item)
 }
 
+// Approve 审核通过报名（后台）
+func (h *RegistrationHandler) Approve(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	if err := h.svc.Approve(c.Request.Context(), id, operatorID); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// Reject 审核拒绝报名（后台）
+func (h *RegistrationHandler) Reject(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	if err := h.svc.Reject(c.Request.Context(), id, operatorID, req.Reason); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
 // Create 创建报名（小程序端）
 func (h *RegistrationHandler) Create(c *gin.Context) {
 	var req service.CreateRegistrationRequest
@@ -89,6 +376,25 @@ func (h *RegistrationHandler) Cancel(c *gin.Context) {
 	response.OK(c, gin.H{"id": id})
 }
 
+// RefundPreview 取消报名前预览可退金额（小程序端）
+func (h *RegistrationHandler) RefundPreview(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	preview, err := h.svc.PreviewRefund(c.Request.Context(), id, userID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, preview)
+}
+
 // MyRegistrations 获取我的报名列表（小程序端）
 func (h *RegistrationHandler) MyRegistrations(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -104,3 +410,13 @@ func (h *RegistrationHandler) MyRegistrations(c *gin.Context) {
 
 	response.PageOK(c, list, total, page, pageSize)
 }
+
+// csvSafeRow 防止 CSV 公式注入：以 =、+、-、@ 或制表符、回车开头的单元格在 Excel 中会被当作公式执行，前面加 ' 按文本显示
+func csvSafeRow(row []string) []string {
+	for i, v := range row {
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			row[i] = "'" + v
+		}
+	}
+	return row
+}
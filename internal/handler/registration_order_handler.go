@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// RegistrationOrderHandler 团体报名订单处理器
+type RegistrationOrderHandler struct {
+	svc *service.RegistrationOrderService
+}
+
+// NewRegistrationOrderHandler 创建团体报名订单处理器
+func NewRegistrationOrderHandler(svc *service.RegistrationOrderService) *RegistrationOrderHandler {
+	return &RegistrationOrderHandler{svc: svc}
+}
+
+// Create 创建团体报名（小程序端）
+func (h *RegistrationOrderHandler) Create(c *gin.Context) {
+	var req service.CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	order, err := h.svc.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, order)
+}
+
+// Get 获取团体报名订单详情（小程序端）
+func (h *RegistrationOrderHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	order, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.NotFound(c, "报名订单不存在")
+		return
+	}
+
+	if order.UserID != int64(c.GetFloat64("user_id")) {
+		response.Forbidden(c, "无权操作")
+		return
+	}
+
+	response.OK(c, order)
+}
+
+// Cancel 取消团体报名中的部分或全部报名人（小程序端）
+func (h *RegistrationOrderHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req struct {
+		RegistrationIDs []int64 `json:"registration_ids"` // 为空表示取消全部
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	if err := h.svc.CancelParticipants(c.Request.Context(), userID, id, req.RegistrationIDs); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"id": id})
+}
+
+// MyOrders 获取我的团体报名订单（小程序端）
+func (h *RegistrationOrderHandler) MyOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	userID := int64(c.GetFloat64("user_id"))
+
+	list, total, err := h.svc.GetByUser(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
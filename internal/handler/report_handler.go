@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// ReportHandler 财务报表处理器
+type ReportHandler struct {
+	svc *service.ReportService
+}
+
+// NewReportHandler 创建财务报表处理器
+func NewReportHandler(svc *service.ReportService) *ReportHandler {
+	return &ReportHandler{svc: svc}
+}
+
+// query 解析报表查询参数: start、end(YYYY-MM-DD，含)、period(day/week/month)、biz_type
+func (h *ReportHandler) query(c *gin.Context) (*service.ReportQuery, bool) {
+	q, err := service.ParseReportQuery(c.Query("start"), c.Query("end"), c.Query("period"), c.Query("biz_type"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return nil, false
+	}
+	return q, true
+}
+
+// Summary 财务汇总（收入、退款、净收入及订单数）
+func (h *ReportHandler) Summary(c *gin.Context) {
+	q, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.svc.Summary(c.Request.Context(), q)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, summary)
+}
+
+// Revenue 按日、周或月的收入趋势
+func (h *ReportHandler) Revenue(c *gin.Context) {
+	q, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.svc.Revenue(c.Request.Context(), q)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, rows)
+}
+
+// ByBizType 按业务类型的收入
+func (h *ReportHandler) ByBizType(c *gin.Context) {
+	q, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.svc.ByBizType(c.Request.Context(), q)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, rows)
+}
+
+// ByActivity 按活动的报名收入
+func (h *ReportHandler) ByActivity(c *gin.Context) {
+	q, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.svc.ByActivity(c.Request.Context(), q)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, rows)
+}
+
+// Export 导出报表 CSV
+// 参数: report(summary/revenue/biz_type/activity)，其余同查询接口
+func (h *ReportHandler) Export(c *gin.Context) {
+	q, ok := h.query(c)
+	if !ok {
+		return
+	}
+
+	table, name, err := h.svc.Export(c.Request.Context(), c.DefaultQuery("report", service.ReportRevenueType), q)
+	if err != nil {
+		if errors.Is(err, errcode.ErrReportTypeInvalid) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name+".csv"))
+	c.Writer.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，便于 Excel 正确识别中文
+	w := csv.NewWriter(c.Writer)
+	w.WriteAll(table)
+	if err := w.Error(); err != nil {
+		c.Error(err)
+	}
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
 	"github.com/zzhtl/go-mountain/internal/pkg/response"
 	"github.com/zzhtl/go-mountain/internal/service"
 )
@@ -18,7 +22,7 @@ func NewSystemConfigHandler(svc *service.SystemConfigService) *SystemConfigHandl
 	return &SystemConfigHandler{svc: svc}
 }
 
-// List 获取所有配置
+// List 获取所有配置（敏感配置的值以掩码返回）
 func (h *SystemConfigHandler) List(c *gin.Context) {
 	group := c.Query("group_name")
 
@@ -51,7 +55,25 @@ func (h *SystemConfigHandler) GetGroups(c *gin.Context) {
 	response.OK(c, groups)
 }
 
-// Save 保存单个配置
+// Schema 获取已知配置项的定义（类型、校验规则、默认值和说明）
+func (h *SystemConfigHandler) Schema(c *gin.Context) {
+	response.OK(c, service.ConfigSchemas())
+}
+
+// saveError 处理保存配置的错误，校验失败时在 data.fields 中返回每个配置项的错误
+func saveError(c *gin.Context, err error) {
+	var verr *service.ConfigValidationError
+	switch {
+	case errors.As(err, &verr):
+		response.FailWithData(c, http.StatusBadRequest, 400, verr.Error(), gin.H{"fields": verr.Fields})
+	case errors.Is(err, errcode.ErrSecretKeyMissing):
+		response.BadRequest(c, err.Error())
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
+
+// Save 保存单个配置，敏感配置提交掩码时保持原值
 func (h *SystemConfigHandler) Save(c *gin.Context) {
 	var req struct {
 		Key       string `json:"key" binding:"required"`
@@ -65,12 +87,8 @@ func (h *SystemConfigHandler) Save(c *gin.Context) {
 		return
 	}
 
-	if req.Type == "" {
-		req.Type = "string"
-	}
-
 	if err := h.svc.SetValue(c.Request.Context(), req.Key, req.Value, req.Type, req.GroupName, req.Remark); err != nil {
-		response.ServerError(c, err.Error())
+		saveError(c, err)
 		return
 	}
 
@@ -88,7 +106,7 @@ func (h *SystemConfigHandler) BatchSave(c *gin.Context) {
 	}
 
 	if err := h.svc.BatchSet(c.Request.Context(), req.Configs); err != nil {
-		response.ServerError(c, err.Error())
+		saveError(c, err)
 		return
 	}
 
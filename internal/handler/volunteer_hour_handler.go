@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/response"
+	"github.com/zzhtl/go-mountain/internal/service"
+)
+
+// VolunteerHourHandler 志愿服务时长处理器
+type VolunteerHourHandler struct {
+	svc *service.VolunteerHourService
+}
+
+// NewVolunteerHourHandler 创建志愿服务时长处理器
+func NewVolunteerHourHandler(svc *service.VolunteerHourService) *VolunteerHourHandler {
+	return &VolunteerHourHandler{svc: svc}
+}
+
+// List 获取时长流水（后台）
+func (h *VolunteerHourHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	userID, _ := strconv.ParseInt(c.Query("user_id"), 10, 64)
+	activityID, _ := strconv.ParseInt(c.Query("activity_id"), 10, 64)
+
+	list, total, err := h.svc.List(c.Request.Context(), page, pageSize, userID, activityID, c.Query("source"))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Create 手工录入时长（后台）
+func (h *VolunteerHourHandler) Create(c *gin.Context) {
+	var req service.CreateVolunteerHourRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	entry, err := h.svc.Create(c.Request.Context(), operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, entry)
+}
+
+// Delete 删除时长记录（后台）
+func (h *VolunteerHourHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// Settle 按活动签到结算时长（后台）
+func (h *VolunteerHourHandler) Settle(c *gin.Context) {
+	var req service.SettleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	operatorID := int64(c.GetFloat64("user_id"))
+
+	created, err := h.svc.Settle(c.Request.Context(), operatorID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{"created": created})
+}
+
+// Totals 按用户汇总累计时长（后台）
+func (h *VolunteerHourHandler) Totals(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	list, total, err := h.svc.Totals(c.Request.Context(), page, pageSize, c.Query("keyword"))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.PageOK(c, list, total, page, pageSize)
+}
+
+// Mine 我的志愿时长：累计汇总和分页流水（小程序端）
+func (h *VolunteerHourHandler) Mine(c *gin.Context) {
+	userID := int64(c.GetFloat64("user_id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	summary, err := h.svc.Summary(c.Request.Context(), userID)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	list, total, err := h.svc.GetByUser(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.OK(c, gin.H{
+		"total_hours":  summary.TotalHours,
+		"record_count": summary.RecordCount,
+		"list":         list,
+		"total":        total,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// Certificate 下载志愿服务证明 PDF（小程序端）
+func (h *VolunteerHourHandler) Certificate(c *gin.Context) {
+	userID := int64(c.GetFloat64("user_id"))
+
+	cert, err := h.svc.IssueCertificate(c.Request.Context(), userID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", `attachment; filename="volunteer-certificate-`+cert.Code+`.pdf"`)
+	c.Header("X-Certificate-Code", cert.Code)
+	c.Status(http.StatusOK)
+	h.svc.WriteCertificatePDF(c.Request.Context(), c.Writer, cert)
+}
+
+// Verify 按验证码核验志愿服务证明（公开接口）
+func (h *VolunteerHourHandler) Verify(c *gin.Context) {
+	result, err := h.svc.Verify(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.OK(c, result)
+}
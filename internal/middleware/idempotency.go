@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader 幂等键请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyEntry 幂等键对应的请求记录
+type idempotencyEntry struct {
+	route       string // 请求方法和路径，同一幂等键不能用于其他接口
+	done        bool   // 首次请求是否已处理完成
+	status      int
+	contentType string
+	body        []byte
+	expireAt    time.Time
+}
+
+// idempotencyStore 进程内幂等记录，过期记录在写入时定期清理
+type idempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// Idempotency 返回幂等键中间件，需放在 JWTAuth 之后
+// 携带 Idempotency-Key 请求头的写操作，同一用户在 ttl 内以相同的键重复提交时直接返回首次请求的响应（附加
+// Idempotent-Replayed: true 响应头），不再重复执行；首次请求仍在处理中时返回 409。
+// 5xx 响应不记录，客户端可使用原键重试。未携带请求头的请求和 GET 请求不受影响
+func Idempotency(ttl time.Duration) gin.HandlerFunc {
+	store := &idempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		ttl:     ttl,
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > 64 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code": 400, "message": "Idempotency-Key 长度不能超过 64 个字符",
+			})
+			return
+		}
+
+		storeKey := fmt.Sprintf("%v:%s", c.GetFloat64("user_id"), key)
+		route := c.Request.Method + " " + c.Request.URL.Path
+
+		entry, created := store.begin(storeKey, route)
+		switch {
+		case entry.route != route:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"code": 422, "message": "Idempotency-Key 已用于其他请求",
+			})
+			return
+		case !created && !entry.done:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"code": 409, "message": "相同 Idempotency-Key 的请求正在处理中",
+			})
+			return
+		case !created:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(entry.status, entry.contentType, entry.body)
+			c.Abort()
+			return
+		}
+
+		// 处理过程中 panic 时移除记录，避免该键一直处于处理中
+		completed := false
+		defer func() {
+			if !completed {
+				store.remove(storeKey)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		store.finish(storeKey, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		completed = true
+	}
+}
+
+// begin 登记幂等键，已存在且未过期时返回已有记录的副本，created 为 false
+func (s *idempotencyStore) begin(key, route string) (entry idempotencyEntry, created bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > s.ttl {
+		for k, e := range s.entries {
+			if e.done && now.After(e.expireAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[key]; ok && (!e.done || now.Before(e.expireAt)) {
+		return *e, false
+	}
+	s.entries[key] = &idempotencyEntry{route: route}
+	return idempotencyEntry{route: route}, true
+}
+
+// finish 记录首次请求的响应，5xx 响应移除记录以便重试
+func (s *idempotencyStore) finish(key string, status int, contentType string, body []byte) {
+	if status >= http.StatusInternalServerError {
+		s.remove(key)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	entry.done = true
+	entry.status = status
+	entry.contentType = contentType
+	entry.body = body
+	entry.expireAt = time.Now().Add(s.ttl)
+}
+
+// remove 移除幂等记录
+func (s *idempotencyStore) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// bodyRecorder 在写出响应的同时保存响应体
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -33,13 +33,11 @@ func RBACAuth(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 获取 role_id
-		roleIDVal, exists := c.Get("role_id")
-		if !exists {
+		if _, exists := c.Get("role_id"); !exists {
 			response.Forbidden(c, "无权限访问")
 			c.Abort()
 			return
 		}
-		roleID := int64(roleIDVal.(float64))
 
 		// 从请求路径和方法解析权限标识
 		permission := resolvePermission(c.FullPath(), c.Request.Method)
@@ -50,14 +48,7 @@ func RBACAuth(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 查询角色是否拥有该 API 权限
-		var count int64
-		db.Model(&model.RoleMenu{}).
-			Joins("INNER JOIN menus ON menus.id = role_menus.menu_id").
-			Where("role_menus.role_id = ? AND menus.permission = ? AND menus.type = 3 AND menus.status = 1",
-				roleID, permission).
-			Count(&count)
-
-		if count == 0 {
+		if !HasPermission(c, db, permission) {
 			response.Forbidden(c, "无权限访问")
 			c.Abort()
 			return
@@ -67,6 +58,40 @@ func RBACAuth(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// HasPermission 判断当前登录的后台用户是否拥有指定权限标识
+// 除路由鉴权外，也用于接口内部的细粒度权限判断（如查看敏感字段）
+func HasPermission(c *gin.Context, db *gorm.DB, permission string) bool {
+	// admin 角色拥有全部权限
+	if roleName, _ := c.Get("role"); roleName == "admin" {
+		return true
+	}
+
+	roleIDVal, exists := c.Get("role_id")
+	if !exists {
+		return false
+	}
+	roleID, ok := roleIDVal.(float64)
+	if !ok {
+		return false
+	}
+
+	var count int64
+	db.Model(&model.RoleMenu{}).
+		Joins("INNER JOIN menus ON menus.id = role_menus.menu_id").
+		Where("role_menus.role_id = ? AND menus.permission = ? AND menus.type = 3 AND menus.status = 1",
+			int64(roleID), permission).
+		Count(&count)
+	return count > 0
+}
+
+// permissionOverrides 无法按路径规则推导的路由权限标识（key: "METHOD 路由路径"）
+var permissionOverrides = map[string]string{
+	"GET /api/admin/registrations/export":         "registration:export",
+	"GET /api/admin/registrations/export-columns": "registration:export",
+	"POST /api/admin/volunteer-hours/settle":      "volunteer_hour:settle",
+	"GET /api/admin/financial-reports/export":     "financial_report:export",
+}
+
 // resolvePermission 从路由路径和HTTP方法解析权限标识
 // 路径格式: /api/admin/{module}/... → 权限: {module}:{action}
 // 示例:
@@ -77,6 +102,10 @@ func RBACAuth(db *gorm.DB) gin.HandlerFunc {
 //	PUT  /api/admin/articles/:id    → article:update
 //	DELETE /api/admin/articles/:id  → article:delete
 func resolvePermission(path, method string) string {
+	if permission, ok := permissionOverrides[method+" "+path]; ok {
+		return permission
+	}
+
 	// 去掉 /api/admin/ 前缀
 	path = strings.TrimPrefix(path, "/api/admin/")
 	if path == "" {
@@ -1,23 +1,37 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
 
 // Activity 活动
 type Activity struct {
 	BaseModel
-	Title           string     `gorm:"type:text;not null" json:"title"`
-	Description     string     `gorm:"type:text" json:"description"`
-	Content         string     `gorm:"type:text" json:"content"`
-	Thumbnail       string     `gorm:"type:text" json:"thumbnail"`
-	Location        string     `gorm:"type:text" json:"location"`
-	StartTime       time.Time  `json:"start_time"`
-	EndTime         time.Time  `json:"end_time"`
-	RegStartTime    *time.Time `json:"reg_start_time,omitempty"`
-	RegEndTime      *time.Time `json:"reg_end_time,omitempty"`
-	MaxParticipants int        `gorm:"default:0" json:"max_participants"` // 0=不限
-	Price           float64    `gorm:"type:decimal(10,2);default:0" json:"price"`
-	Status          int        `gorm:"default:0" json:"status"` // 0:草稿 1:报名中 2:报名截止 3:进行中 4:已结束
-	CreatedBy       int64      `json:"created_by"`
+	GeoLocation
+	Title             string          `gorm:"type:text;not null" json:"title"`
+	Description       string          `gorm:"type:text" json:"description"`
+	Content           string          `gorm:"type:text" json:"content"`
+	Thumbnail         string          `gorm:"type:text" json:"thumbnail"`
+	Location          string          `gorm:"type:text" json:"location"`
+	StartTime         time.Time       `json:"start_time"`
+	EndTime           time.Time       `json:"end_time"`
+	RegStartTime      *time.Time      `json:"reg_start_time,omitempty"`
+	RegEndTime        *time.Time      `json:"reg_end_time,omitempty"`
+	MaxParticipants   int             `gorm:"default:0" json:"max_participants"` // 0=不限
+	Price             money.Money     `gorm:"type:bigint;default:0" json:"price"`
+	RequiresApproval  bool            `gorm:"default:false" json:"requires_approval"`  // 报名需组织者审核
+	RefundFullDays    int             `gorm:"default:0" json:"refund_full_days"`       // 开始前 N 天及以上取消全额退款
+	RefundPartialDays int             `gorm:"default:0" json:"refund_partial_days"`    // 开始前 N 天及以上取消按比例退款，此后不退款
+	RefundPartialRate int             `gorm:"default:0" json:"refund_partial_rate"`    // 部分退款比例（百分比）
+	FormSchema        json.RawMessage `gorm:"type:jsonb" json:"form_schema,omitempty"` // 报名表单附加字段定义，报名时填写到 ExtraInfo
+	SeriesID          *int64          `gorm:"index" json:"series_id,omitempty"`        // 所属周期活动系列
+	Status            int             `gorm:"default:0" json:"status"`                 // 0:草稿 1:报名中 2:报名截止 3:进行中 4:已结束
+	Sequence          int             `gorm:"default:0" json:"sequence"`               // 修改次数，作为日历订阅事件的 SEQUENCE
+	PublishedAt       *time.Time      `json:"published_at,omitempty"`                  // 首次发布时间，撤回发布或删除后公开日历据此输出取消事件
+	CreatedBy         int64           `json:"created_by"`
 }
 
 func (Activity) TableName() string {
@@ -0,0 +1,23 @@
+package model
+
+import "encoding/json"
+
+// ActivityReview 活动评价（每条已确认报名可评价一次）
+type ActivityReview struct {
+	BaseModel
+	ActivityID     int64           `gorm:"not null;index" json:"activity_id"`
+	RegistrationID int64           `gorm:"not null;uniqueIndex" json:"registration_id"`
+	UserID         int64           `gorm:"not null;index" json:"user_id"`
+	Rating         int             `gorm:"not null" json:"rating"` // 1-5 星
+	Content        string          `gorm:"type:text" json:"content"`
+	Images         json.RawMessage `gorm:"type:jsonb" json:"images,omitempty"` // 图片 URL 数组
+	Status         int             `gorm:"default:1" json:"status"`            // 0:隐藏 1:显示
+	Featured       bool            `gorm:"default:false" json:"featured"`      // 精选，列表中置顶
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ActivityReview) TableName() string {
+	return "activity_reviews"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ActivitySeries 周期活动系列，记录生成各期活动所用的模板和重复规则
+type ActivitySeries struct {
+	BaseModel
+	TemplateID        int64     `gorm:"not null;index" json:"template_id"`
+	RRule             string    `gorm:"type:text;not null" json:"rrule"` // RFC 5545 RRULE 子集
+	DTStart           time.Time `json:"dtstart"`                         // 第一期开始时间
+	RegOpenBeforeMin  *int      `json:"reg_open_before_min,omitempty"`   // 开始前多少分钟开放报名，为空不限制
+	RegCloseBeforeMin *int      `json:"reg_close_before_min,omitempty"`  // 开始前多少分钟截止报名，为空不限制
+	CreatedBy         int64     `json:"created_by"`
+}
+
+func (ActivitySeries) TableName() string {
+	return "activity_series"
+}
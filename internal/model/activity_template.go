@@ -0,0 +1,33 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// ActivityTemplate 活动模板（可复用的活动内容、票种和报名表单）
+type ActivityTemplate struct {
+	BaseModel
+	GeoLocation
+	Name              string          `gorm:"type:text;not null" json:"name"`
+	Title             string          `gorm:"type:text;not null" json:"title"`
+	Description       string          `gorm:"type:text" json:"description"`
+	Content           string          `gorm:"type:text" json:"content"`
+	Thumbnail         string          `gorm:"type:text" json:"thumbnail"`
+	Location          string          `gorm:"type:text" json:"location"`
+	DurationMinutes   int             `gorm:"default:0" json:"duration_minutes"` // 活动时长，用于推算结束时间
+	MaxParticipants   int             `gorm:"default:0" json:"max_participants"` // 0=不限
+	Price             money.Money     `gorm:"type:bigint;default:0" json:"price"`
+	RequiresApproval  bool            `gorm:"default:false" json:"requires_approval"`
+	RefundFullDays    int             `gorm:"default:0" json:"refund_full_days"`
+	RefundPartialDays int             `gorm:"default:0" json:"refund_partial_days"`
+	RefundPartialRate int             `gorm:"default:0" json:"refund_partial_rate"`
+	FormSchema        json.RawMessage `gorm:"type:jsonb" json:"form_schema,omitempty"`
+	Tickets           json.RawMessage `gorm:"type:jsonb" json:"tickets,omitempty"` // 票种定义（不含售卖时间）
+	CreatedBy         int64           `json:"created_by"`
+}
+
+func (ActivityTemplate) TableName() string {
+	return "activity_templates"
+}
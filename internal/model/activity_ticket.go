@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// ActivityTicket 活动票种（成人票/儿童票/早鸟票/会员票等）
+type ActivityTicket struct {
+	BaseModel
+	ActivityID    int64       `gorm:"not null;index" json:"activity_id"`
+	Name          string      `gorm:"type:text;not null" json:"name"`
+	Description   string      `gorm:"type:text" json:"description"`
+	Price         money.Money `gorm:"type:bigint;default:0" json:"price"`
+	Quota         int         `gorm:"default:0" json:"quota"` // 0=不限
+	SaleStartTime *time.Time  `json:"sale_start_time,omitempty"`
+	SaleEndTime   *time.Time  `json:"sale_end_time,omitempty"`
+	Sort          int         `gorm:"default:0" json:"sort"`
+	Status        int         `gorm:"default:1" json:"status"` // 0:停售 1:在售
+}
+
+func (ActivityTicket) TableName() string {
+	return "activity_tickets"
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// DonationCampaign 募捐项目
+type DonationCampaign struct {
+	BaseModel
+	Title         string          `gorm:"type:text;not null" json:"title"`
+	Description   string          `gorm:"type:text" json:"description"`
+	Content       string          `gorm:"type:text" json:"content"`
+	Cover         string          `gorm:"type:text" json:"cover"`
+	GoalAmount    money.Money     `gorm:"type:bigint;default:0" json:"goal_amount"`   // 目标金额，0 表示不设目标
+	PresetAmounts json.RawMessage `gorm:"type:jsonb" json:"preset_amounts,omitempty"` // 预设捐赠金额，如 [10, 50, 100]
+	StartTime     *time.Time      `json:"start_time,omitempty"`
+	EndTime       *time.Time      `json:"end_time,omitempty"`
+	Status        int             `gorm:"default:0" json:"status"` // 0:草稿 1:进行中 2:已结束
+	CreatedBy     int64           `json:"created_by"`
+}
+
+func (DonationCampaign) TableName() string {
+	return "donation_campaigns"
+}
+
+// Donation 捐赠记录，支付成功后计入募捐进度
+type Donation struct {
+	BaseModel
+	CampaignID int64       `gorm:"not null;index" json:"campaign_id"`
+	UserID     int64       `gorm:"not null;index" json:"user_id"`
+	Amount     money.Money `gorm:"type:bigint;not null" json:"amount"`
+	Message    string      `gorm:"type:text" json:"message"`       // 捐赠留言
+	Anonymous  bool        `gorm:"default:false" json:"anonymous"` // 匿名捐赠，捐赠墙不展示姓名
+	Status     int         `gorm:"default:0" json:"status"`        // 0:待支付 1:已支付 3:已退款
+	PaymentID  *int64      `json:"payment_id,omitempty"`
+	PaidAt     *time.Time  `json:"paid_at,omitempty"`
+
+	// 关联
+	Campaign *DonationCampaign `gorm:"foreignKey:CampaignID" json:"campaign,omitempty"`
+}
+
+func (Donation) TableName() string {
+	return "donations"
+}
+
+// DonationReceipt 捐赠收据，每笔支付成功的捐赠开具一张，退款后作废
+type DonationReceipt struct {
+	BaseModel
+	SerialNo   string      `gorm:"type:text;uniqueIndex;not null" json:"serial_no"` // 收据编号
+	DonationID int64       `gorm:"not null;uniqueIndex" json:"donation_id"`
+	CampaignID int64       `gorm:"not null;index" json:"campaign_id"`
+	UserID     int64       `gorm:"not null;index" json:"user_id"`
+	DonorName  string      `gorm:"type:text;not null" json:"donor_name"`
+	Amount     money.Money `gorm:"type:bigint;not null" json:"amount"`
+	IssuedAt   time.Time   `json:"issued_at"`
+	Status     int         `gorm:"default:1" json:"status"` // 0:已作废 1:有效
+	VoidedAt   *time.Time  `json:"voided_at,omitempty"`
+	VoidReason string      `gorm:"type:text" json:"void_reason,omitempty"`
+}
+
+func (DonationReceipt) TableName() string {
+	return "donation_receipts"
+}
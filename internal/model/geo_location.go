@@ -0,0 +1,12 @@
+package model
+
+// GeoLocation 结构化地理位置（嵌入活动和活动模板），Location 字段仍保留为展示用的地点名称
+// 坐标统一使用 GCJ-02（微信小程序 wx.getLocation type=gcj02 返回的坐标系）
+type GeoLocation struct {
+	Latitude         *float64 `gorm:"index" json:"latitude,omitempty"`
+	Longitude        *float64 `json:"longitude,omitempty"`
+	Address          string   `gorm:"type:text" json:"address"`
+	MeetingPoint     string   `gorm:"type:text" json:"meeting_point"` // 集合地点（可选）
+	MeetingLatitude  *float64 `json:"meeting_latitude,omitempty"`
+	MeetingLongitude *float64 `json:"meeting_longitude,omitempty"`
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
 )
 
 // Payment 支付记录
@@ -11,18 +13,20 @@ type Payment struct {
 	OrderNo       string          `gorm:"type:text;uniqueIndex;not null" json:"order_no"`
 	TransactionID string          `gorm:"type:text" json:"transaction_id"`
 	UserID        int64           `gorm:"not null;index" json:"user_id"`
-	Amount        float64         `gorm:"type:decimal(10,2);not null" json:"amount"`
-	PayType       string          `gorm:"type:text;not null" json:"pay_type"` // wechat_jsapi
-	Status        int             `gorm:"default:0" json:"status"`                   // 0:待支付 1:已支付 2:已退款 3:支付失败
-	BizType       string          `gorm:"type:text;not null" json:"biz_type"` // registration/donation
+	Amount        money.Money     `gorm:"type:bigint;not null" json:"amount"`
+	RefundAmount  money.Money     `gorm:"type:bigint;default:0" json:"refund_amount"` // 累计已退款金额（退款成功的流水合计）
+	PayType       string          `gorm:"type:text;not null" json:"pay_type"`         // wechat_jsapi/wechat_native/wechat_h5/mock/offline
+	Status        int             `gorm:"default:0" json:"status"`                    // 0:待支付 1:已支付 2:已退款 3:支付失败 4:部分退款
+	BizType       string          `gorm:"type:text;not null" json:"biz_type"`         // registration/registration_order/donation
 	BizID         int64           `gorm:"not null" json:"biz_id"`
 	PrepayID      string          `gorm:"type:text" json:"prepay_id"`
-	PaidAt        *time.Time      `json:"paid_at,omitempty"`
+	PaidAt        *time.Time      `gorm:"index" json:"paid_at,omitempty"`
 	RefundAt      *time.Time      `json:"refund_at,omitempty"`
 	NotifyData    json.RawMessage `gorm:"type:jsonb" json:"notify_data,omitempty"`
 
 	// 关联
-	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Refunds []Refund `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
 }
 
 func (Payment) TableName() string {
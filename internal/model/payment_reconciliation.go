@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// PaymentReconciliation 微信支付对账报告，每个账单日一份，重新对账时覆盖
+type PaymentReconciliation struct {
+	BaseModel
+	BillDate      string          `gorm:"type:text;uniqueIndex;not null" json:"bill_date"` // 账单日期 YYYY-MM-DD
+	Status        int             `gorm:"default:0" json:"status"`                         // 0:对账中 1:已完成 2:对账失败
+	TradeCount    int             `gorm:"default:0" json:"trade_count"`                    // 账单支付笔数
+	TradeAmount   money.Money     `gorm:"type:bigint;default:0" json:"trade_amount"`
+	RefundCount   int             `gorm:"default:0" json:"refund_count"` // 账单退款笔数
+	RefundAmount  money.Money     `gorm:"type:bigint;default:0" json:"refund_amount"`
+	MismatchCount int             `gorm:"default:0" json:"mismatch_count"`
+	Mismatches    json.RawMessage `gorm:"type:jsonb" json:"mismatches,omitempty"` // 差异明细
+	Error         string          `gorm:"type:text" json:"error,omitempty"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+}
+
+func (PaymentReconciliation) TableName() string {
+	return "payment_reconciliations"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// Refund 退款流水，一笔支付可多次部分退款，支付记录的累计退款金额和状态由流水汇总得出
+type Refund struct {
+	BaseModel
+	OutRefundNo     string          `gorm:"type:text;uniqueIndex;not null" json:"out_refund_no"` // 商户退款单号
+	PaymentID       int64           `gorm:"not null;index" json:"payment_id"`
+	Amount          money.Money     `gorm:"type:bigint;not null" json:"amount"`
+	Reason          string          `gorm:"type:text" json:"reason"`
+	OperatorID      *int64          `json:"operator_id,omitempty"`                  // 操作的后台用户，用户自助取消时为空
+	RefundID        string          `gorm:"type:text" json:"refund_id,omitempty"`   // 微信退款单号
+	Status          int             `gorm:"default:0;index" json:"status"`          // 0:退款中 1:退款成功 2:退款关闭 3:退款异常
+	FailReason      string          `gorm:"type:text" json:"fail_reason,omitempty"` // 退款关闭或异常原因
+	SuccessAt       *time.Time      `gorm:"index" json:"success_at,omitempty"`
+	ReceivedAccount string          `gorm:"type:text" json:"received_account,omitempty"` // 退款入账账户
+	NotifyData      json.RawMessage `gorm:"type:jsonb" json:"notify_data,omitempty"`     // 最近一次退款结果通知
+
+	// 关联
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+func (Refund) TableName() string {
+	return "refunds"
+}
@@ -1,22 +1,43 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
 
 // Registration 报名记录
 type Registration struct {
 	BaseModel
-	ActivityID int64           `gorm:"not null;index" json:"activity_id"`
-	UserID     int64           `gorm:"not null;index" json:"user_id"`
-	Name       string          `gorm:"type:text;not null" json:"name"`
-	Phone      string          `gorm:"type:text;not null" json:"phone"`
-	IDCard     string          `gorm:"type:text" json:"id_card"`
-	ExtraInfo  json.RawMessage `gorm:"type:jsonb" json:"extra_info,omitempty"`
-	Status     int             `gorm:"default:0" json:"status"` // 0:待支付 1:已支付 2:已取消 3:已退款
-	PaymentID  *int64          `json:"payment_id,omitempty"`
+	ActivityID   int64           `gorm:"not null;index" json:"activity_id"`
+	TicketID     *int64          `gorm:"index" json:"ticket_id,omitempty"`
+	UserID       int64           `gorm:"not null;index" json:"user_id"`
+	OrderID      *int64          `gorm:"index" json:"order_id,omitempty"` // 团体报名所属订单
+	Name         string          `gorm:"type:text;not null" json:"name"`
+	Phone        string          `gorm:"type:text;not null" json:"phone"`
+	IDCard       string          `gorm:"type:text" json:"id_card"`
+	ExtraInfo    json.RawMessage `gorm:"type:jsonb" json:"extra_info,omitempty"`
+	Amount       money.Money     `gorm:"type:bigint;default:0" json:"amount"` // 报名时锁定的应付金额
+	Status       int             `gorm:"default:0" json:"status"`             // 0:待支付 1:已支付 2:已取消 3:已退款 4:待审核 5:审核未通过
+	PaymentID    *int64          `json:"payment_id,omitempty"`
+	RefundAmount money.Money     `gorm:"type:bigint;default:0" json:"refund_amount"` // 取消时按退款规则应退的金额，退款关闭时清零
+	RefundID     *int64          `gorm:"index" json:"refund_id,omitempty"`           // 取消时发起的退款流水，退款成功后报名转为已退款
+	CreatedBy    *int64          `json:"created_by,omitempty"`                       // 后台代报名的操作人，小程序报名为空
+
+	// 审核
+	ReviewReason string     `gorm:"type:text" json:"review_reason,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy   *int64     `json:"reviewed_by,omitempty"`
+
+	// 签到
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInBy *int64     `json:"checked_in_by,omitempty"` // 扫码核销的后台用户
 
 	// 关联
-	Activity *Activity `gorm:"foreignKey:ActivityID" json:"activity,omitempty"`
-	User     *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Activity *Activity       `gorm:"foreignKey:ActivityID" json:"activity,omitempty"`
+	Ticket   *ActivityTicket `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
+	User     *User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 func (Registration) TableName() string {
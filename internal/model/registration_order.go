@@ -0,0 +1,21 @@
+package model
+
+import "github.com/zzhtl/go-mountain/internal/pkg/money"
+
+// RegistrationOrder 报名订单（一次提交多名报名人，合并支付）
+type RegistrationOrder struct {
+	BaseModel
+	ActivityID  int64       `gorm:"not null;index" json:"activity_id"`
+	UserID      int64       `gorm:"not null;index" json:"user_id"`
+	TotalAmount money.Money `gorm:"type:bigint;default:0" json:"total_amount"` // 未取消报名人的应付合计
+	Status      int         `gorm:"default:0" json:"status"`                   // 0:待支付 1:已支付 2:已取消 3:已退款
+	PaymentID   *int64      `json:"payment_id,omitempty"`
+
+	// 关联
+	Activity     *Activity      `gorm:"foreignKey:ActivityID" json:"activity,omitempty"`
+	Participants []Registration `gorm:"foreignKey:OrderID" json:"participants,omitempty"`
+}
+
+func (RegistrationOrder) TableName() string {
+	return "registration_orders"
+}
@@ -10,6 +10,8 @@ type User struct {
 	Avatar  string `gorm:"type:text" json:"avatar"`
 	Gender  int    `gorm:"default:0" json:"gender"`
 	Status  int    `gorm:"default:1" json:"status"`
+
+	CalendarToken string `gorm:"type:text;index" json:"-"` // 个人日历订阅令牌
 }
 
 func (User) TableName() string {
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// VolunteerHour 志愿服务时长流水
+// 来源为活动签到时关联报名记录（同一用户每个活动只记一次），后台手工录入时报名为空，时长可为负数用于冲正
+type VolunteerHour struct {
+	BaseModel
+	UserID         int64     `gorm:"not null;index" json:"user_id"`
+	ActivityID     *int64    `gorm:"index" json:"activity_id,omitempty"`
+	RegistrationID *int64    `gorm:"uniqueIndex" json:"registration_id,omitempty"`
+	Hours          float64   `gorm:"type:decimal(8,2);not null" json:"hours"`
+	ServiceDate    time.Time `json:"service_date"`
+	Source         string    `gorm:"type:text;not null" json:"source"` // attendance:活动签到 manual:手工录入
+	Remark         string    `gorm:"type:text" json:"remark"`
+	CreatedBy      *int64    `json:"created_by,omitempty"` // 录入或结算的后台用户
+
+	// 关联
+	Activity *Activity `gorm:"foreignKey:ActivityID" json:"activity,omitempty"`
+	User     *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (VolunteerHour) TableName() string {
+	return "volunteer_hours"
+}
+
+// VolunteerCertificate 志愿服务证明，记录开具时的累计时长供公开核验
+type VolunteerCertificate struct {
+	BaseModel
+	Code        string    `gorm:"type:text;uniqueIndex;not null" json:"code"` // 验证码
+	UserID      int64     `gorm:"not null;index" json:"user_id"`
+	Name        string    `gorm:"type:text;not null" json:"name"`
+	TotalHours  float64   `gorm:"type:decimal(10,2);not null" json:"total_hours"`
+	RecordCount int64     `gorm:"not null" json:"record_count"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
+func (VolunteerCertificate) TableName() string {
+	return "volunteer_certificates"
+}
@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix 密文前缀，用于区分已加密的值和历史明文
+const encryptedPrefix = "enc:v1:"
+
+// ErrDecrypt 密文无法解密（主密钥不匹配或数据被篡改）
+var ErrDecrypt = errors.New("解密失败：主密钥不匹配或密文已损坏")
+
+// Cipher AES-256-GCM 加密器，用于加密存储敏感配置
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher 使用 base64 编码的 32 字节主密钥创建加密器（可用 openssl rand -base64 32 生成）
+func NewCipher(masterKey string) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(masterKey))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("主密钥格式错误：应为 base64 编码的 32 字节随机数")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文，associated 为绑定的附加数据（如配置 key），解密时须一致，防止密文被挪用到其他配置项
+// 返回 "enc:v1:" + base64(随机 nonce + 密文)
+func (c *Cipher) Encrypt(plaintext, associated string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(associated))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 生成的密文
+func (c *Cipher) Decrypt(ciphertext, associated string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, encryptedPrefix)
+	if !ok {
+		return "", ErrDecrypt
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, []byte(associated))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断值是否为 Encrypt 生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
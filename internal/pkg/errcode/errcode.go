@@ -22,17 +22,63 @@ var (
 
 // 业务错误
 var (
-	ErrActivityNotOpen     = errors.New("活动未开放报名")
-	ErrActivityFull        = errors.New("活动报名已满")
-	ErrAlreadyRegistered   = errors.New("已经报名过该活动")
-	ErrPaymentFailed       = errors.New("支付失败")
-	ErrColumnHasArticles   = errors.New("该栏目下存在文章，无法删除")
-	ErrMenuHasChildren     = errors.New("存在子菜单，无法删除")
-	ErrRoleInUse              = errors.New("该角色正在被使用，无法删除")
-	ErrActivityHasRegistrations = errors.New("活动存在有效报名，无法删除")
-	ErrRegistrationNotFound   = errors.New("报名记录不存在")
-	ErrRegistrationCancelled  = errors.New("报名已取消")
-	ErrPaymentNotFound        = errors.New("支付记录不存在")
-	ErrPaymentAlreadyPaid     = errors.New("订单已支付")
-	ErrRefundFailed           = errors.New("退款失败")
+	ErrActivityNotOpen              = errors.New("活动未开放报名")
+	ErrActivityFull                 = errors.New("活动报名已满")
+	ErrAlreadyRegistered            = errors.New("已经报名过该活动")
+	ErrPaymentFailed                = errors.New("支付失败")
+	ErrColumnHasArticles            = errors.New("该栏目下存在文章，无法删除")
+	ErrMenuHasChildren              = errors.New("存在子菜单，无法删除")
+	ErrRoleInUse                    = errors.New("该角色正在被使用，无法删除")
+	ErrActivityHasRegistrations     = errors.New("活动存在有效报名，无法删除")
+	ErrRegistrationNotFound         = errors.New("报名记录不存在")
+	ErrRegistrationCancelled        = errors.New("报名已取消")
+	ErrPaymentNotFound              = errors.New("支付记录不存在")
+	ErrPaymentAlreadyPaid           = errors.New("订单已支付")
+	ErrRefundFailed                 = errors.New("退款失败")
+	ErrTicketRequired               = errors.New("请选择票种")
+	ErrTicketNotFound               = errors.New("票种不存在")
+	ErrTicketNotOnSale              = errors.New("该票种当前不可购买")
+	ErrTicketSoldOut                = errors.New("该票种已售罄")
+	ErrTicketHasRegistrations       = errors.New("票种存在有效报名，无法删除")
+	ErrDuplicateParticipant         = errors.New("报名人已报名该活动")
+	ErrRegistrationInOrder          = errors.New("该报名属于团体订单，请通过订单取消")
+	ErrOrderNotFound                = errors.New("报名订单不存在")
+	ErrOrderStatusInvalid           = errors.New("报名订单状态不支持该操作")
+	ErrRefundAmountInvalid          = errors.New("退款金额超出可退金额")
+	ErrCheckinTokenInvalid          = errors.New("无效的签到码")
+	ErrCheckinWrongActivity         = errors.New("签到码不属于该活动")
+	ErrCheckinNotAllowed            = errors.New("报名未确认，无法签到")
+	ErrAlreadyCheckedIn             = errors.New("已签到，请勿重复签到")
+	ErrRegistrationPendingReview    = errors.New("报名审核中，审核通过后方可支付")
+	ErrRegistrationRejected         = errors.New("报名审核未通过")
+	ErrRegistrationNotPayable       = errors.New("该报名记录状态不支持支付")
+	ErrRegistrationNotPendingReview = errors.New("该报名不在待审核状态")
+	ErrExportColumnInvalid          = errors.New("不支持的导出列")
+	ErrTemplateNotFound             = errors.New("活动模板不存在")
+	ErrRecurrenceInvalid            = errors.New("无效的重复规则")
+	ErrReviewNotAllowed             = errors.New("仅已确认报名可在活动结束后评价")
+	ErrAlreadyReviewed              = errors.New("该报名已评价")
+	ErrReviewNotFound               = errors.New("评价不存在")
+	ErrVolunteerHourNotFound        = errors.New("志愿时长记录不存在")
+	ErrVolunteerHoursInvalid        = errors.New("服务时长无效")
+	ErrNoVolunteerHours             = errors.New("暂无志愿服务时长，无法开具证明")
+	ErrCertificateNotFound          = errors.New("证明不存在或验证码错误")
+	ErrCampaignNotFound             = errors.New("募捐项目不存在")
+	ErrCampaignNotOpen              = errors.New("募捐项目未在进行中")
+	ErrCampaignHasDonations         = errors.New("募捐项目已有捐赠记录，无法删除")
+	ErrDonationAmountInvalid        = errors.New("捐赠金额无效")
+	ErrReceiptNotFound              = errors.New("捐赠收据不存在")
+	ErrReceiptVoided                = errors.New("捐赠收据已作废")
+	ErrRefundNotFound               = errors.New("退款记录不存在")
+	ErrReconciliationNotFound       = errors.New("对账报告不存在")
+	ErrBillDateInvalid              = errors.New("账单日期格式应为 YYYY-MM-DD，且须早于今天")
+	ErrMockPayDisabled              = errors.New("模拟支付未启用，请将系统配置 payment.provider 设为 mock")
+	ErrPaymentPaying                = errors.New("订单支付中，请稍后查询支付结果")
+	ErrReportRangeInvalid           = errors.New("统计日期格式应为 YYYY-MM-DD，截止日期不能早于起始日期，且跨度不超过 3 年")
+	ErrReportPeriodInvalid          = errors.New("统计周期应为 day、week 或 month")
+	ErrReportTypeInvalid            = errors.New("不支持的报表类型")
+	ErrSecretKeyMissing             = errors.New("未配置主密钥（SECRET_MASTER_KEY），无法保存敏感配置")
+	ErrConfigInvalid                = errors.New("配置校验失败")
+	ErrDonationPartialRefund        = errors.New("捐赠只支持全额退款（收据按捐赠全额开具）")
+	ErrTicketSaleTimeInvalid        = errors.New("票种售卖结束时间不能早于开始时间")
 )
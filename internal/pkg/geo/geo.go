@@ -0,0 +1,65 @@
+// Package geo 提供经纬度距离计算和范围查询所需的外接矩形
+// 距离计算在 Go 中完成，不依赖数据库空间扩展，SQLite 和 PostgreSQL 通用
+package geo
+
+import "math"
+
+// earthRadiusKm 地球平均半径（千米）
+const earthRadiusKm = 6371.0088
+
+// kmPerDegreeLat 每纬度对应的距离（千米）
+const kmPerDegreeLat = math.Pi * earthRadiusKm / 180
+
+// Distance 使用 Haversine 公式计算两点间的球面距离（千米）
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	φ1 := lat1 * math.Pi / 180
+	φ2 := lat2 * math.Pi / 180
+	dφ := (lat2 - lat1) * math.Pi / 180
+	dλ := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Box 经纬度外接矩形，用于在数据库中按索引粗筛
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+	// AllLng 为 true 时经度不做限制（半径覆盖极点）
+	AllLng bool
+	// WrapLng 为 true 时经度范围跨越 ±180°，应匹配 lng >= MinLng OR lng <= MaxLng
+	WrapLng bool
+}
+
+// BoundingBox 计算以 (lat, lng) 为圆心、半径 radiusKm 的外接矩形
+func BoundingBox(lat, lng, radiusKm float64) Box {
+	dLat := radiusKm / kmPerDegreeLat
+	box := Box{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+	}
+
+	// 矩形触及极点时经度覆盖全部范围
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.AllLng = true
+		return box
+	}
+
+	dLng := radiusKm / (kmPerDegreeLat * math.Cos(lat*math.Pi/180))
+	if dLng >= 180 {
+		box.AllLng = true
+		return box
+	}
+
+	box.MinLng = lng - dLng
+	box.MaxLng = lng + dLng
+	if box.MinLng < -180 {
+		box.MinLng += 360
+		box.WrapLng = true
+	}
+	if box.MaxLng > 180 {
+		box.MaxLng -= 360
+		box.WrapLng = true
+	}
+	return box
+}
@@ -0,0 +1,129 @@
+// Package ical 生成 RFC 5545 iCalendar（.ics）订阅内容
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 事件状态
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// maxLineOctets 内容行最大字节数，超出时折行
+const maxLineOctets = 75
+
+// Event 日历事件（VEVENT）
+// UID 在同一事件的多次输出间保持不变，修改或取消时递增 Sequence，日历客户端据此更新已有事件
+type Event struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Status      string
+	Updated     time.Time
+}
+
+// Calendar 日历（VCALENDAR）
+type Calendar struct {
+	Name   string
+	ProdID string
+	Events []Event
+}
+
+// WriteTo 以 CRLF 换行输出日历内容
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	line := func(name, value string) {
+		fold(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", escape(c.ProdID))
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	now := time.Now()
+	for _, e := range c.Events {
+		stamp := e.Updated
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("SEQUENCE", fmt.Sprint(e.Sequence))
+		line("DTSTAMP", formatTime(stamp))
+		line("LAST-MODIFIED", formatTime(stamp))
+		line("DTSTART", formatTime(e.Start))
+		if !e.End.After(e.Start) {
+			// 未设置结束时间时按一小时处理，避免客户端显示为零时长事件
+			line("DTEND", formatTime(e.Start.Add(time.Hour)))
+		} else {
+			line("DTEND", formatTime(e.End))
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if e.Status != "" {
+			line("STATUS", e.Status)
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// formatTime 统一输出为 UTC 时间
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape 转义 TEXT 类型值中的特殊字符
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// fold 按 75 字节折行写入内容行，续行以空格开头，且不拆分多字节字符
+func fold(b *strings.Builder, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// 续行首个空格占用一个字节
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
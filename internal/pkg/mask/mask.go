@@ -0,0 +1,35 @@
+// Package mask 提供手机号、证件号等敏感信息的脱敏处理
+package mask
+
+import "strings"
+
+// Phone 手机号脱敏，保留前 3 位和后 4 位，如 138****1234
+func Phone(s string) string {
+	return keep(s, 3, 4)
+}
+
+// IDCard 证件号脱敏，保留前 3 位和后 4 位，如 110***********1234
+func IDCard(s string) string {
+	return keep(s, 3, 4)
+}
+
+// Name 姓名脱敏，仅保留第一个字，如 张**
+func Name(s string) string {
+	return keep(s, 1, 0)
+}
+
+// keep 保留前 head 位和后 tail 位，中间替换为 *；长度不足时全部替换
+func keep(s string, head, tail int) string {
+	runes := []rune(s)
+	n := len(runes)
+	if n == 0 {
+		return ""
+	}
+	if n <= head+tail {
+		if n == 1 {
+			return "*"
+		}
+		return string(runes[:1]) + strings.Repeat("*", n-1)
+	}
+	return string(runes[:head]) + strings.Repeat("*", n-head-tail) + string(runes[n-tail:])
+}
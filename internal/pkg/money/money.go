@@ -0,0 +1,166 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 人民币金额，以分为单位的整数存储和计算，避免浮点误差
+// JSON 中以元为单位的数字输入输出（如 12.34），数据库中存储为分（bigint）
+type Money int64
+
+// FromYuan 将以元为单位的浮点金额四舍五入到分
+func FromYuan(yuan float64) Money {
+	return Money(math.Round(yuan * 100))
+}
+
+// Fen 以分为单位的金额
+func (m Money) Fen() int64 {
+	return int64(m)
+}
+
+// Yuan 以元为单位的金额，仅用于展示或比例计算
+func (m Money) Yuan() float64 {
+	return float64(m) / 100
+}
+
+// String 格式化为两位小数的元，如 12.30
+func (m Money) String() string {
+	sign := ""
+	fen := int64(m)
+	if fen < 0 {
+		sign, fen = "-", -fen
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, fen/100, fen%100)
+}
+
+// Percent 按百分比计算金额，向下取整到分（用于按比例退款，避免超额）
+func (m Money) Percent(rate int) Money {
+	return Money(int64(m) * int64(rate) / 100)
+}
+
+// Upper 中文大写金额
+func (m Money) Upper() string {
+	return Upper(int64(m))
+}
+
+// Parse 解析以元为单位的十进制金额字符串，超过两位小数的部分四舍五入
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("金额为空")
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg, s = true, s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("无效的金额: %s", s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if strings.Trim(intPart, "0123456789") != "" || strings.Trim(fracPart, "0123456789") != "" {
+		return 0, fmt.Errorf("无效的金额: %s", s)
+	}
+
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || yuan > math.MaxInt64/100-1 {
+		return 0, fmt.Errorf("金额超出范围: %s", s)
+	}
+
+	// 取前三位小数，第三位用于四舍五入
+	frac := (fracPart + "000")[:3]
+	n, _ := strconv.ParseInt(frac, 10, 64)
+	fen := yuan*100 + n/10
+	if n%10 >= 5 {
+		fen++
+	}
+
+	if neg {
+		fen = -fen
+	}
+	return Money(fen), nil
+}
+
+// MarshalJSON 输出以元为单位的数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受以元为单位的数字或数字字符串，null 视为 0
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	// 兼容科学计数法等 Parse 不支持的数字写法
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("无效的金额: %s", s)
+		}
+		*m = FromYuan(f)
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 以分存入数据库
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan 从数据库读取以分为单位的整数（SUM 等聚合结果可能为浮点或数字字符串）
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("money: 不支持的类型 %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*m = Money(n)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("money: 无法解析 %q", s)
+	}
+	*m = Money(math.Round(f))
+	return nil
+}
+
+// GormDataType 数据库列类型
+func (Money) GormDataType() string {
+	return "bigint"
+}
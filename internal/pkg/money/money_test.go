@@ -0,0 +1,189 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+	}{
+		{"0", 0},
+		{"12", 1200},
+		{"12.3", 1230},
+		{"12.34", 1234},
+		{"12.345", 1235}, // 第三位小数四舍五入
+		{"12.344", 1234},
+		{"0.005", 1},
+		{"0.004", 0},
+		{".5", 50},
+		{"5.", 500},
+		{"+1.01", 101},
+		{"-1.01", -101},
+		{"-0.015", -2},
+		{" 99.99 ", 9999},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "-", ".", "abc", "1.2.3", "1,000", "1e3", "92233720368547758"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestFromYuan(t *testing.T) {
+	cases := []struct {
+		in   float64
+		want Money
+	}{
+		{0.1 + 0.2, 30}, // 浮点误差四舍五入到分
+		{19.99, 1999},
+		{1.005, 100}, // 1.005 的二进制表示略小于 1.005
+		{-2.5, -250},
+		{0.015, 2},
+	}
+	for _, tc := range cases {
+		if got := FromYuan(tc.in); got != tc.want {
+			t.Errorf("FromYuan(%v) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1230, "12.30"},
+		{-5, "-0.05"},
+		{-123456, "-1234.56"},
+	}
+	for _, tc := range cases {
+		if got := tc.in.String(); got != tc.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	cases := []struct {
+		amount Money
+		rate   int
+		want   Money
+	}{
+		{1000, 50, 500},
+		{999, 50, 499}, // 向下取整到分，避免超额退款
+		{1, 99, 0},
+		{1234, 100, 1234},
+		{1234, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := tc.amount.Percent(tc.rate); got != tc.want {
+			t.Errorf("Money(%d).Percent(%d) = %d, want %d", tc.amount, tc.rate, got, tc.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type payload struct {
+		Amount Money `json:"amount"`
+	}
+	for _, m := range []Money{0, 1, 10, 1234, -1, -1234, 100000000} {
+		data, err := json.Marshal(payload{Amount: m})
+		if err != nil {
+			t.Fatalf("Marshal(%d): %v", m, err)
+		}
+		var got payload
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if got.Amount != m {
+			t.Errorf("round trip %d via %s = %d", m, data, got.Amount)
+		}
+	}
+
+	if data, _ := json.Marshal(payload{Amount: 1230}); string(data) != `{"amount":12.30}` {
+		t.Errorf("Marshal = %s, want {\"amount\":12.30}", data)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+	}{
+		{`12.34`, 1234},
+		{`"12.34"`, 1234},
+		{`12.345`, 1235},
+		{`-0.5`, -50},
+		{`null`, 0},
+		{`1e2`, 10000},
+		{`1.5E-1`, 15},
+	}
+	for _, tc := range cases {
+		var m Money = 99
+		if err := json.Unmarshal([]byte(tc.in), &m); err != nil {
+			t.Errorf("Unmarshal(%s) error: %v", tc.in, err)
+			continue
+		}
+		if m != tc.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tc.in, m, tc.want)
+		}
+	}
+
+	for _, in := range []string{`"abc"`, `true`, `"1e"`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", in)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	cases := []struct {
+		in   any
+		want Money
+	}{
+		{nil, 0},
+		{int64(1234), 1234},
+		{int64(-5), -5},
+		{float64(1234.4), 1234}, // 聚合结果为浮点时四舍五入
+		{float64(1234.5), 1235},
+		{[]byte("1234"), 1234},
+		{"1234.0", 1234},
+	}
+	for _, tc := range cases {
+		var m Money = 99
+		if err := m.Scan(tc.in); err != nil {
+			t.Errorf("Scan(%#v) error: %v", tc.in, err)
+			continue
+		}
+		if m != tc.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tc.in, m, tc.want)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool) should fail")
+	}
+	if err := m.Scan("abc"); err == nil {
+		t.Error(`Scan("abc") should fail`)
+	}
+}
@@ -0,0 +1,69 @@
+// Package money 提供以分为单位的人民币金额类型及格式化工具
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	upperDigits   = []string{"零", "壹", "贰", "叁", "肆", "伍", "陆", "柒", "捌", "玖"}
+	upperUnits    = []string{"", "拾", "佰", "仟"}
+	upperSections = []string{"", "万", "亿", "万亿"}
+)
+
+// Upper 将以分为单位的金额转换为中文大写，如 10005 分 → 壹佰元零伍分
+func Upper(fen int64) string {
+	if fen < 0 {
+		return "负" + Upper(-fen)
+	}
+
+	yuan, jiao, cent := fen/100, fen/10%10, fen%10
+
+	var b strings.Builder
+	if yuan > 0 {
+		b.WriteString(upperInt(yuan))
+		b.WriteString("元")
+	}
+	if jiao == 0 && cent == 0 {
+		if yuan == 0 {
+			return "零元整"
+		}
+		b.WriteString("整")
+		return b.String()
+	}
+	if jiao > 0 {
+		b.WriteString(upperDigits[jiao] + "角")
+	} else if yuan > 0 {
+		b.WriteString("零")
+	}
+	if cent > 0 {
+		b.WriteString(upperDigits[cent] + "分")
+	}
+	return b.String()
+}
+
+// upperInt 转换正整数部分，按四位一节处理万、亿，节内和跨节的连续零合并为一个零
+func upperInt(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	zero := false
+	for i := 0; i < len(s); i++ {
+		d := s[i] - '0'
+		pos := len(s) - 1 - i
+		if d == 0 {
+			zero = true
+		} else {
+			if zero {
+				b.WriteString("零")
+				zero = false
+			}
+			b.WriteString(upperDigits[d] + upperUnits[pos%4])
+		}
+		// 节末尾：本节有非零数字时追加节单位
+		if pos%4 == 0 && pos > 0 && strings.Trim(s[max(0, i-3):i+1], "0") != "" {
+			b.WriteString(upperSections[pos/4])
+		}
+	}
+	return b.String()
+}
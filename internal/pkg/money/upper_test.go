@@ -0,0 +1,36 @@
+package money
+
+import "testing"
+
+func TestUpper(t *testing.T) {
+	cases := []struct {
+		fen  int64
+		want string
+	}{
+		{0, "零元整"},
+		{5, "伍分"},
+		{10, "壹角"},
+		{15, "壹角伍分"},
+		{100, "壹元整"},
+		{1010, "壹拾元壹角"},
+		{10005, "壹佰元零伍分"},
+		{10050, "壹佰元伍角"},
+		{100100, "壹仟零壹元整"},
+		{123456, "壹仟贰佰叁拾肆元伍角陆分"},
+		{1000000, "壹万元整"},
+		{1000100, "壹万零壹元整"},
+		{10010000, "壹拾万零壹佰元整"},
+		{10000000000, "壹亿元整"},
+		{10000100000, "壹亿零壹仟元整"},
+		{10001000000, "壹亿零壹万元整"},
+		{-150, "负壹元伍角"},
+	}
+	for _, tc := range cases {
+		if got := Upper(tc.fen); got != tc.want {
+			t.Errorf("Upper(%d) = %q, want %q", tc.fen, got, tc.want)
+		}
+		if got := Money(tc.fen).Upper(); got != tc.want {
+			t.Errorf("Money(%d).Upper() = %q, want %q", tc.fen, got, tc.want)
+		}
+	}
+}
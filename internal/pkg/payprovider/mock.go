@@ -0,0 +1,178 @@
+package payprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Mock 模拟支付渠道，用于本地联调报名到支付的完整流程
+//
+// 下单只在内存中登记，退款直接受理，不产生真实资金往来；支付结果和退款结果通过向模拟回调接口
+// 提交 JSON 通知来模拟，trade_state / refund_status 缺省为 SUCCESS，可传入失败状态模拟支付失败、退款关闭等情况。
+// 状态仅保存在内存中，服务重启后未通知的订单查询结果为未支付
+type Mock struct {
+	mu     sync.Mutex
+	orders map[string]*Order
+}
+
+// NewMock 创建模拟支付渠道
+func NewMock() *Mock {
+	return &Mock{orders: make(map[string]*Order)}
+}
+
+// MockPaidNotify 模拟支付结果通知
+type MockPaidNotify struct {
+	OutTradeNo    string `json:"out_trade_no"`
+	TradeState    string `json:"trade_state"` // SUCCESS / PAYERROR / CLOSED，缺省为 SUCCESS
+	TransactionID string `json:"transaction_id"`
+}
+
+// MockRefundNotify 模拟退款结果通知
+type MockRefundNotify struct {
+	OutRefundNo  string `json:"out_refund_no"`
+	RefundStatus string `json:"refund_status"` // SUCCESS / CLOSED / ABNORMAL，缺省为 SUCCESS
+}
+
+// PayType 实现 Provider，各下单场景均记为模拟支付
+func (m *Mock) PayType(tradeType string) (string, error) {
+	switch tradeType {
+	case "", TradeTypeJSAPI, TradeTypeNative, TradeTypeH5:
+		return PayTypeMock, nil
+	}
+	return "", fmt.Errorf("模拟支付不支持的下单场景: %s", tradeType)
+}
+
+// Prepay 登记待支付订单，返回的支付参数仅用于标识模拟支付
+func (m *Mock) Prepay(ctx context.Context, req *PrepayRequest) (*PrepayResult, error) {
+	if _, err := m.PayType(req.TradeType); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prepayID := "mock_" + req.OrderNo
+	m.orders[req.OrderNo] = &Order{
+		OrderNo:    req.OrderNo,
+		TradeState: TradeNotPay,
+		Amount:     req.Amount,
+	}
+	payParams, _ := m.PayParams(PayTypeMock, req.OrderNo, prepayID)
+	return &PrepayResult{PayType: PayTypeMock, PrepayID: prepayID, PayParams: payParams}, nil
+}
+
+// PayParams 返回标识模拟支付的参数
+func (m *Mock) PayParams(payType, orderNo, prepayID string) (any, error) {
+	return map[string]any{
+		"mock":      true,
+		"order_no":  orderNo,
+		"prepay_id": prepayID,
+	}, nil
+}
+
+// Query 查询内存中的模拟订单，未登记的订单视为未支付
+func (m *Mock) Query(ctx context.Context, orderNo string) (*Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderNo]
+	if !ok {
+		order = &Order{OrderNo: orderNo, TradeState: TradeNotPay}
+	}
+	result := *order
+	result.Raw = marshal(order)
+	return &result, nil
+}
+
+// Close 关闭未支付的模拟订单
+func (m *Mock) Close(ctx context.Context, orderNo string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderNo]
+	if !ok {
+		return nil
+	}
+	if order.TradeState == TradeSuccess {
+		return errors.New("模拟订单已支付，无法关闭")
+	}
+	order.TradeState = TradeClosed
+	return nil
+}
+
+// Refund 受理退款，结果为退款中，需通过模拟退款通知更新
+func (m *Mock) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	return &RefundResult{RefundID: "mock_" + req.OutRefundNo, Status: RefundProcessing}, nil
+}
+
+// ParsePaidNotify 解析模拟支付结果通知
+func (m *Mock) ParsePaidNotify(r *http.Request) (*Order, error) {
+	var n MockPaidNotify
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		return nil, fmt.Errorf("通知数据格式错误: %w", err)
+	}
+	if n.OutTradeNo == "" {
+		return nil, errors.New("缺少 out_trade_no")
+	}
+	switch n.TradeState {
+	case "":
+		n.TradeState = TradeSuccess
+	case TradeSuccess, TradePayError, TradeClosed:
+	default:
+		return nil, fmt.Errorf("不支持的交易状态 %s", n.TradeState)
+	}
+	if n.TransactionID == "" && n.TradeState == TradeSuccess {
+		n.TransactionID = "mock_" + n.OutTradeNo
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[n.OutTradeNo]
+	if !ok {
+		order = &Order{OrderNo: n.OutTradeNo}
+		m.orders[n.OutTradeNo] = order
+	}
+	order.TradeState = n.TradeState
+	order.TransactionID = n.TransactionID
+
+	result := *order
+	result.Raw = marshal(n)
+	return &result, nil
+}
+
+// ParseRefundNotify 解析模拟退款结果通知
+func (m *Mock) ParseRefundNotify(r *http.Request) (*RefundNotify, error) {
+	var n MockRefundNotify
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		return nil, fmt.Errorf("通知数据格式错误: %w", err)
+	}
+	if n.OutRefundNo == "" {
+		return nil, errors.New("缺少 out_refund_no")
+	}
+	switch n.RefundStatus {
+	case "":
+		n.RefundStatus = RefundSuccess
+	case RefundSuccess, RefundClosed, RefundAbnormal:
+	default:
+		return nil, fmt.Errorf("不支持的退款状态 %s", n.RefundStatus)
+	}
+
+	notify := &RefundNotify{
+		OutRefundNo:  n.OutRefundNo,
+		RefundID:     "mock_" + n.OutRefundNo,
+		RefundStatus: n.RefundStatus,
+		Raw:          marshal(n),
+	}
+	if n.RefundStatus == RefundSuccess {
+		now := time.Now()
+		notify.SuccessTime = &now
+		notify.ReceivedAccount = "模拟支付零钱"
+	}
+	return notify, nil
+}
@@ -0,0 +1,139 @@
+// Package payprovider 支付渠道抽象
+//
+// 业务层通过 Provider 完成下单、查单、关单、退款和回调解析，不直接依赖具体支付 SDK。
+// 目前提供微信支付（Wechat）和用于本地联调的模拟支付（Mock）两种实现
+package payprovider
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// 下单场景
+const (
+	TradeTypeJSAPI  = "jsapi"  // 小程序内支付
+	TradeTypeNative = "native" // 网站扫码支付
+	TradeTypeH5     = "h5"     // 手机浏览器支付
+)
+
+// 支付方式，对应 payments.pay_type
+const (
+	PayTypeWechatJSAPI  = "wechat_jsapi"
+	PayTypeWechatNative = "wechat_native"
+	PayTypeWechatH5     = "wechat_h5"
+	PayTypeMock         = "mock"
+)
+
+// 支付渠道名称
+const (
+	ProviderWechat = "wechat"
+	ProviderMock   = "mock"
+)
+
+// WechatPayTypes 微信支付渠道的全部支付方式，查单、回调和对账不区分下单场景
+var WechatPayTypes = []string{PayTypeWechatJSAPI, PayTypeWechatNative, PayTypeWechatH5}
+
+// ProviderOf 返回支付方式所属的渠道名称，线下收款等非渠道支付方式返回空字符串
+func ProviderOf(payType string) string {
+	switch payType {
+	case PayTypeWechatJSAPI, PayTypeWechatNative, PayTypeWechatH5:
+		return ProviderWechat
+	case PayTypeMock:
+		return ProviderMock
+	}
+	return ""
+}
+
+// 渠道订单交易状态（与微信支付一致）
+const (
+	TradeSuccess    = "SUCCESS"
+	TradeNotPay     = "NOTPAY"
+	TradeUserPaying = "USERPAYING"
+	TradeClosed     = "CLOSED"
+	TradeRevoked    = "REVOKED"
+	TradePayError   = "PAYERROR"
+)
+
+// 渠道退款状态（与微信支付一致）
+const (
+	RefundSuccess    = "SUCCESS"
+	RefundProcessing = "PROCESSING"
+	RefundClosed     = "CLOSED"
+	RefundAbnormal   = "ABNORMAL"
+)
+
+// Provider 支付渠道
+type Provider interface {
+	// PayType 下单场景对应的支付方式，写入支付记录用于后续查单、退款时选择渠道；不支持的场景返回错误
+	PayType(tradeType string) (string, error)
+	// Prepay 下单，返回前端拉起支付所需的参数
+	Prepay(ctx context.Context, req *PrepayRequest) (*PrepayResult, error)
+	// PayParams 按已有的预支付单重新生成拉起支付的参数，用于复用未过期的订单
+	PayParams(payType, orderNo, prepayID string) (any, error)
+	// Query 按商户订单号查询渠道订单
+	Query(ctx context.Context, orderNo string) (*Order, error)
+	// Close 关闭未支付的渠道订单
+	Close(ctx context.Context, orderNo string) error
+	// Refund 申请退款，渠道受理后一般为退款中，结果以退款通知为准
+	Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error)
+	// ParsePaidNotify 验签并解析支付结果通知
+	ParsePaidNotify(r *http.Request) (*Order, error)
+	// ParseRefundNotify 验签并解析退款结果通知
+	ParseRefundNotify(r *http.Request) (*RefundNotify, error)
+}
+
+// PrepayRequest 下单请求
+type PrepayRequest struct {
+	OrderNo     string
+	TradeType   string // 下单场景，为空时按 JSAPI 处理
+	Description string
+	Amount      money.Money
+	OpenID      string // JSAPI 下单的用户 openid
+	ClientIP    string // 用户终端 IP，H5 下单必填
+	ExpireAt    time.Time
+}
+
+// PrepayResult 下单结果
+type PrepayResult struct {
+	PayType   string
+	PrepayID  string // JSAPI 为 prepay_id，Native 为二维码链接 code_url，H5 为支付跳转链接 h5_url
+	PayParams any    // 前端拉起支付所需的参数
+}
+
+// Order 渠道订单
+type Order struct {
+	OrderNo       string
+	TransactionID string
+	TradeState    string
+	Amount        money.Money
+	Raw           []byte // 渠道原始数据，用于存档
+}
+
+// RefundRequest 退款请求
+type RefundRequest struct {
+	OrderNo       string
+	TransactionID string
+	OutRefundNo   string
+	Reason        string
+	Amount        money.Money // 本次退款金额
+	Total         money.Money // 原支付金额
+}
+
+// RefundResult 退款受理结果
+type RefundResult struct {
+	RefundID string
+	Status   string
+}
+
+// RefundNotify 退款结果通知
+type RefundNotify struct {
+	OutRefundNo     string
+	RefundID        string
+	RefundStatus    string
+	SuccessTime     *time.Time
+	ReceivedAccount string
+	Raw             []byte
+}
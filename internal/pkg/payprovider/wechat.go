@@ -0,0 +1,521 @@
+package payprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ArtisanCloud/PowerWeChat/v3/src/kernel/models"
+	"github.com/ArtisanCloud/PowerWeChat/v3/src/kernel/power"
+	"github.com/ArtisanCloud/PowerWeChat/v3/src/kernel/support"
+	"github.com/ArtisanCloud/PowerWeChat/v3/src/payment"
+	notifyRequest "github.com/ArtisanCloud/PowerWeChat/v3/src/payment/notify/request"
+	orderRequest "github.com/ArtisanCloud/PowerWeChat/v3/src/payment/order/request"
+	refundRequest "github.com/ArtisanCloud/PowerWeChat/v3/src/payment/refund/request"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// WechatConfig 微信支付配置
+type WechatConfig struct {
+	AppID           string
+	MchID           string
+	MchAPIv3Key     string
+	SerialNo        string
+	PrivateKey      string // 商户私钥内容（PEM 格式）
+	NotifyURL       string
+	RefundNotifyURL string // 为空时使用商户平台配置
+}
+
+// Wechat 微信支付渠道（基于 PowerWeChat），支持 JSAPI、Native 和 H5 下单
+//
+// 实例可长期复用：商户私钥写入权限为 0600 的文件，在 Release 前一直保留供 PowerWeChat 签名时读取；
+// 微信支付平台证书首次验签时下载并缓存，用于校验回调通知的签名
+type Wechat struct {
+	app             *payment.Payment
+	apiV3Key        string
+	refundNotifyURL string
+	keyFile         string
+
+	certMu       sync.Mutex
+	certs        map[string]*x509.Certificate // 平台证书序列号 -> 证书
+	certsAt      time.Time                    // 最近一次下载平台证书的时间
+	certsTriedAt time.Time                    // 最近一次尝试下载平台证书的时间
+}
+
+// 平台证书缓存刷新策略：定期刷新以获取新证书；遇到未知序列号时立即刷新，但限制频率防止伪造请求触发频繁下载
+const (
+	certRefreshInterval = 12 * time.Hour
+	certRetryInterval   = time.Minute
+	notifyMaxSkew       = 5 * time.Minute // 回调时间戳允许的最大偏差
+)
+
+// NewWechat 创建微信支付渠道，使用完毕（如配置变更被替换）后需调用 Release 清理私钥文件
+func NewWechat(cfg WechatConfig) (*Wechat, error) {
+	if cfg.AppID == "" || cfg.MchID == "" || cfg.MchAPIv3Key == "" || cfg.PrivateKey == "" {
+		return nil, errors.New("微信支付配置不完整，请在后台【系统配置】中完善微信支付相关配置")
+	}
+	// 先在内存中解析私钥，格式错误时直接返回，避免到首次下单签名时才暴露
+	if err := checkPrivateKey(cfg.PrivateKey); err != nil {
+		return nil, err
+	}
+
+	// PowerWeChat 需要私钥文件路径，文件仅当前用户可读写
+	keyFile, err := os.CreateTemp("", "wechat_mch_key_*.pem")
+	if err != nil {
+		return nil, fmt.Errorf("创建密钥文件失败: %w", err)
+	}
+	if err := keyFile.Chmod(0o600); err == nil {
+		_, err = keyFile.WriteString(cfg.PrivateKey)
+	}
+	keyFile.Close()
+	if err != nil {
+		os.Remove(keyFile.Name())
+		return nil, fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+
+	app, err := payment.NewPayment(&payment.UserConfig{
+		AppID:       cfg.AppID,
+		MchID:       cfg.MchID,
+		MchApiV3Key: cfg.MchAPIv3Key,
+		KeyPath:     keyFile.Name(),
+		SerialNo:    cfg.SerialNo,
+		NotifyURL:   cfg.NotifyURL,
+	})
+	if err != nil {
+		os.Remove(keyFile.Name())
+		return nil, fmt.Errorf("初始化微信支付实例失败: %w", err)
+	}
+
+	return &Wechat{
+		app:             app,
+		apiV3Key:        cfg.MchAPIv3Key,
+		refundNotifyURL: cfg.RefundNotifyURL,
+		keyFile:         keyFile.Name(),
+	}, nil
+}
+
+// Release 删除私钥文件，之后实例不可再用于需要签名的请求
+func (w *Wechat) Release() error {
+	return os.Remove(w.keyFile)
+}
+
+// checkPrivateKey 校验商户私钥为 PKCS#8 格式的 RSA 私钥（商户平台下载的 apiclient_key.pem）
+func checkPrivateKey(privateKey string) error {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return errors.New("商户私钥格式错误：不是有效的 PEM 内容")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("商户私钥格式错误: %w", err)
+	}
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		return errors.New("商户私钥格式错误：不是 RSA 私钥")
+	}
+	return nil
+}
+
+// PayType 实现 Provider，支持 JSAPI、Native 和 H5 下单
+func (w *Wechat) PayType(tradeType string) (string, error) {
+	switch tradeType {
+	case "", TradeTypeJSAPI:
+		return PayTypeWechatJSAPI, nil
+	case TradeTypeNative:
+		return PayTypeWechatNative, nil
+	case TradeTypeH5:
+		return PayTypeWechatH5, nil
+	}
+	return "", fmt.Errorf("微信支付不支持的下单场景: %s", tradeType)
+}
+
+// Prepay 按下单场景调用 JSAPI、Native 或 H5 下单，返回前端拉起支付所需的参数
+func (w *Wechat) Prepay(ctx context.Context, req *PrepayRequest) (*PrepayResult, error) {
+	payType, err := w.PayType(req.TradeType)
+	if err != nil {
+		return nil, err
+	}
+
+	var prepayID string
+	switch payType {
+	case PayTypeWechatNative:
+		prepayID, err = w.prepayNative(ctx, req)
+	case PayTypeWechatH5:
+		prepayID, err = w.prepayH5(ctx, req)
+	default:
+		prepayID, err = w.prepayJSAPI(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payParams, err := w.PayParams(payType, req.OrderNo, prepayID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrepayResult{PayType: payType, PrepayID: prepayID, PayParams: payParams}, nil
+}
+
+// prepayJSAPI JSAPI 下单，返回 prepay_id
+func (w *Wechat) prepayJSAPI(ctx context.Context, req *PrepayRequest) (string, error) {
+	if req.OpenID == "" {
+		return "", errors.New("微信下单失败: 缺少用户 openid，请在小程序内支付")
+	}
+	result, err := w.app.Order.JSAPITransaction(ctx, &orderRequest.RequestJSAPIPrepay{
+		Description: req.Description,
+		OutTradeNo:  req.OrderNo,
+		TimeExpire:  req.ExpireAt.Format(time.RFC3339),
+		Amount: &orderRequest.JSAPIAmount{
+			Total:    int(req.Amount.Fen()),
+			Currency: "CNY",
+		},
+		Payer: &orderRequest.JSAPIPayer{
+			OpenID: req.OpenID,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("微信下单失败: %w", err)
+	}
+	if result.PrepayID == "" {
+		return "", errors.New("微信下单失败: 未获取到 prepay_id")
+	}
+	return result.PrepayID, nil
+}
+
+// prepayNative Native 下单，返回用于生成支付二维码的 code_url（有效期 2 小时）
+func (w *Wechat) prepayNative(ctx context.Context, req *PrepayRequest) (string, error) {
+	result, err := w.app.Order.TransactionNative(ctx, &orderRequest.RequestNativePrepay{
+		Description: req.Description,
+		OutTradeNo:  req.OrderNo,
+		TimeExpire:  req.ExpireAt.Format(time.RFC3339),
+		Amount: &orderRequest.NativeAmount{
+			Total:    int(req.Amount.Fen()),
+			Currency: "CNY",
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("微信下单失败: %w", err)
+	}
+	if result.CodeURL == "" {
+		return "", errors.New("微信下单失败: 未获取到 code_url")
+	}
+	return result.CodeURL, nil
+}
+
+// prepayH5 H5 下单，返回跳转微信收银台的 h5_url（有效期 5 分钟）
+func (w *Wechat) prepayH5(ctx context.Context, req *PrepayRequest) (string, error) {
+	if req.ClientIP == "" {
+		return "", errors.New("微信下单失败: 缺少用户终端 IP")
+	}
+	result, err := w.app.Order.TransactionH5(ctx, &orderRequest.RequestH5Prepay{
+		Description: req.Description,
+		OutTradeNo:  req.OrderNo,
+		TimeExpire:  req.ExpireAt.Format(time.RFC3339),
+		Amount: &orderRequest.H5Amount{
+			Total:    int(req.Amount.Fen()),
+			Currency: "CNY",
+		},
+		SceneInfo: &orderRequest.H5SceneInfo{
+			PayerClientIP: req.ClientIP,
+			H5Info:        &orderRequest.H5H5Info{Type: "Wap"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("微信下单失败: %w", err)
+	}
+	if result.H5URL == "" {
+		return "", errors.New("微信下单失败: 未获取到 h5_url")
+	}
+	return result.H5URL, nil
+}
+
+// PayParams 生成前端拉起支付所需的参数：JSAPI 重新签名生成小程序支付参数，
+// Native 返回二维码链接 code_url，H5 返回支付跳转链接 h5_url
+func (w *Wechat) PayParams(payType, orderNo, prepayID string) (any, error) {
+	switch payType {
+	case PayTypeWechatNative:
+		return map[string]string{"code_url": prepayID}, nil
+	case PayTypeWechatH5:
+		return map[string]string{"h5_url": prepayID}, nil
+	}
+	payParams, err := w.app.JSSDK.BridgeConfig(prepayID, false)
+	if err != nil {
+		return nil, fmt.Errorf("生成支付参数失败: %w", err)
+	}
+	return payParams, nil
+}
+
+// Query 按商户订单号查询微信订单
+func (w *Wechat) Query(ctx context.Context, orderNo string) (*Order, error) {
+	result, err := w.app.Order.QueryByOutTradeNumber(ctx, orderNo)
+	if err != nil {
+		return nil, fmt.Errorf("查询微信订单失败: %w", err)
+	}
+	if result.TradeState == "" {
+		return nil, fmt.Errorf("查询微信订单失败: %s", result.Message)
+	}
+
+	order := &Order{
+		OrderNo:       result.OutTradeNo,
+		TransactionID: result.TransactionID,
+		TradeState:    result.TradeState,
+		Raw:           marshal(result),
+	}
+	if result.Amount != nil {
+		order.Amount = money.Money(result.Amount.Total)
+	}
+	return order, nil
+}
+
+// Close 关闭微信订单
+func (w *Wechat) Close(ctx context.Context, orderNo string) error {
+	if _, err := w.app.Order.Close(ctx, orderNo); err != nil {
+		return fmt.Errorf("关闭微信订单失败: %w", err)
+	}
+	return nil
+}
+
+// Refund 申请微信退款
+func (w *Wechat) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	result, err := w.app.Refund.Refund(ctx, &refundRequest.RequestRefund{
+		TransactionID: req.TransactionID,
+		OutRefundNo:   req.OutRefundNo,
+		Reason:        req.Reason,
+		NotifyUrl:     w.refundNotifyURL,
+		Amount: &refundRequest.RefundAmount{
+			Refund:   int(req.Amount.Fen()),
+			Total:    int(req.Total.Fen()),
+			Currency: "CNY",
+		},
+	})
+	if err == nil && result.RefundID == "" {
+		err = fmt.Errorf("未获取到 refund_id %s", result.Message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("微信退款失败: %w", err)
+	}
+	return &RefundResult{RefundID: result.RefundID, Status: result.Status}, nil
+}
+
+// ParsePaidNotify 校验平台签名后使用 PowerWeChat 解密支付结果通知
+func (w *Wechat) ParsePaidNotify(r *http.Request) (*Order, error) {
+	if err := w.verifyNotify(r); err != nil {
+		return nil, err
+	}
+
+	var transaction *models.Transaction
+	_, err := w.app.HandlePaidNotify(r,
+		func(message *notifyRequest.RequestNotify, t *models.Transaction, fail func(message string)) interface{} {
+			transaction = t
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if transaction == nil {
+		return nil, errors.New("交易数据为空")
+	}
+
+	order := &Order{
+		OrderNo:       transaction.OutTradeNo,
+		TransactionID: transaction.TransactionID,
+		TradeState:    transaction.TradeState,
+		Raw:           marshal(transaction),
+	}
+	if transaction.Amount != nil {
+		order.Amount = money.Money(transaction.Amount.Total)
+	}
+	return order, nil
+}
+
+// ParseRefundNotify 校验平台签名后使用 PowerWeChat 解密退款结果通知
+func (w *Wechat) ParseRefundNotify(r *http.Request) (*RefundNotify, error) {
+	if err := w.verifyNotify(r); err != nil {
+		return nil, err
+	}
+
+	var refund *models.Refund
+	_, err := w.app.HandleRefundedNotify(r,
+		func(message *notifyRequest.RequestNotify, t *models.Refund, fail func(message string)) interface{} {
+			refund = t
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if refund == nil {
+		return nil, errors.New("退款数据为空")
+	}
+
+	return &RefundNotify{
+		OutRefundNo:     refund.OutRefundNo,
+		RefundID:        refund.RefundID,
+		RefundStatus:    refund.RefundStatus,
+		SuccessTime:     refund.SuccessTime,
+		ReceivedAccount: refund.UserReceivedAccount,
+		Raw:             marshal(refund),
+	}, nil
+}
+
+// verifyNotify 使用微信支付平台证书校验回调通知的签名和时间戳，校验后恢复请求体供后续解密
+func (w *Wechat) verifyNotify(r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("读取通知数据失败: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	serial := r.Header.Get("Wechatpay-Serial")
+	signature := r.Header.Get("Wechatpay-Signature")
+	timestamp := r.Header.Get("Wechatpay-Timestamp")
+	nonce := r.Header.Get("Wechatpay-Nonce")
+	if serial == "" || signature == "" || timestamp == "" || nonce == "" {
+		return errors.New("通知缺少签名信息")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("通知时间戳格式错误")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > notifyMaxSkew || skew < -notifyMaxSkew {
+		return errors.New("通知时间戳已过期")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("通知签名格式错误")
+	}
+
+	cert, err := w.platformCert(r.Context(), serial)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("平台证书公钥类型错误")
+	}
+
+	hashed := sha256.Sum256([]byte(timestamp + "\n" + nonce + "\n" + string(body) + "\n"))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sig); err != nil {
+		return errors.New("通知签名校验失败")
+	}
+	return nil
+}
+
+// platformCert 按序列号获取平台证书，缓存过期或遇到未知序列号（平台证书轮换）时重新下载
+func (w *Wechat) platformCert(ctx context.Context, serial string) (*x509.Certificate, error) {
+	w.certMu.Lock()
+	defer w.certMu.Unlock()
+
+	cert, ok := w.certs[serial]
+	if ok && time.Since(w.certsAt) < certRefreshInterval {
+		return cert, nil
+	}
+	if time.Since(w.certsTriedAt) < certRetryInterval {
+		if ok {
+			return cert, nil
+		}
+		return nil, fmt.Errorf("未知的平台证书序列号 %s", serial)
+	}
+
+	w.certsTriedAt = time.Now()
+	certs, err := w.downloadCerts(ctx)
+	if err != nil {
+		// 下载失败时继续使用已缓存的证书
+		if ok {
+			return cert, nil
+		}
+		return nil, err
+	}
+	w.certs, w.certsAt = certs, time.Now()
+
+	if cert, ok = certs[serial]; !ok {
+		return nil, fmt.Errorf("未知的平台证书序列号 %s", serial)
+	}
+	return cert, nil
+}
+
+// downloadCerts 下载并解密微信支付平台证书
+func (w *Wechat) downloadCerts(ctx context.Context) (map[string]*x509.Certificate, error) {
+	result, err := w.app.Security.GetCertificates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("下载平台证书失败: %w", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, errors.New("下载平台证书失败: 未返回证书")
+	}
+
+	certs := make(map[string]*x509.Certificate, len(result.Data))
+	for _, c := range result.Data {
+		enc := c.EncryptCertificate
+		plain, err := support.DecryptAES256GCM(w.apiV3Key, enc.AssociatedData, enc.Nonce, enc.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("解密平台证书失败: %w", err)
+		}
+		block, _ := pem.Decode([]byte(plain))
+		if block == nil {
+			return nil, errors.New("解析平台证书失败")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析平台证书失败: %w", err)
+		}
+		certs[c.SerialNo] = cert
+	}
+	return certs, nil
+}
+
+// TradeBill 申请并下载指定日期（yyyy-MM-dd）的交易账单（全部交易类型），当日无交易时返回 nil
+func (w *Wechat) TradeBill(ctx context.Context, billDate string) ([]byte, error) {
+	bill, err := w.app.Bill.GetTradeBill(ctx, billDate, "ALL", "")
+	if err != nil {
+		return nil, fmt.Errorf("申请交易账单失败: %w", err)
+	}
+	if bill.Code == "NO_STATEMENT_EXIST" {
+		return nil, nil
+	}
+	if bill.DownloadURL == "" {
+		return nil, fmt.Errorf("申请交易账单失败: %s", bill.Message)
+	}
+
+	file, err := os.CreateTemp("", "wechat_trade_bill_*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("创建账单临时文件失败: %w", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	_, err = w.app.Bill.DownloadBill(ctx, &power.RequestDownload{
+		HashType:    bill.HashType,
+		HashValue:   bill.HashValue,
+		DownloadURL: bill.DownloadURL,
+	}, file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("下载交易账单失败: %w", err)
+	}
+
+	return os.ReadFile(file.Name())
+}
+
+// marshal 将渠道数据序列化为 JSON 用于存储
+func marshal(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
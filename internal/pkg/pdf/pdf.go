@@ -0,0 +1,169 @@
+// Package pdf 生成包含中文文本和简单线框的单字体 PDF 文档
+//
+// 中文使用 PDF 阅读器内置的 Adobe-GB1 字体 STSong-Light（UniGB-UCS2-H 编码），
+// 无需嵌入字体文件，生成的文档体积小，适合证书、收据等简单版式
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 纸张尺寸（单位: pt）
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Document PDF 文档
+type Document struct {
+	width  float64
+	height float64
+	pages  []*Page
+}
+
+// New 创建指定页面尺寸的文档，横向 A4 传入 New(A4Height, A4Width)
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+// Width 页面宽度
+func (d *Document) Width() float64 {
+	return d.width
+}
+
+// Height 页面高度
+func (d *Document) Height() float64 {
+	return d.height
+}
+
+// AddPage 追加一页，坐标原点在页面左下角
+func (d *Document) AddPage() *Page {
+	p := &Page{width: d.width}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Page 文档页面，按调用顺序记录绘制指令
+type Page struct {
+	width float64
+	buf   bytes.Buffer
+}
+
+// SetFillColor 设置文字和填充颜色（RGB 分量取值 0-1）
+func (p *Page) SetFillColor(r, g, b float64) {
+	fmt.Fprintf(&p.buf, "%s %s %s rg\n", num(r), num(g), num(b))
+}
+
+// SetStrokeColor 设置线条颜色（RGB 分量取值 0-1）
+func (p *Page) SetStrokeColor(r, g, b float64) {
+	fmt.Fprintf(&p.buf, "%s %s %s RG\n", num(r), num(g), num(b))
+}
+
+// Line 绘制直线
+func (p *Page) Line(x1, y1, x2, y2, lineWidth float64) {
+	fmt.Fprintf(&p.buf, "%s w %s %s m %s %s l S\n", num(lineWidth), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Rect 绘制矩形边框，(x, y) 为左下角
+func (p *Page) Rect(x, y, w, h, lineWidth float64) {
+	fmt.Fprintf(&p.buf, "%s w %s %s %s %s re S\n", num(lineWidth), num(x), num(y), num(w), num(h))
+}
+
+// Text 在 (x, y) 处绘制单行文本，y 为基线位置
+func (p *Page) Text(x, y, size float64, s string) {
+	fmt.Fprintf(&p.buf, "BT /F1 %s Tf %s %s Td <%s> Tj ET\n", num(size), num(x), num(y), encode(s))
+}
+
+// TextCenter 绘制水平居中的单行文本
+func (p *Page) TextCenter(y, size float64, s string) {
+	p.Text((p.width-TextWidth(s, size))/2, y, size, s)
+}
+
+// TextWidth 估算文本宽度：ASCII 字符按半角、其余按全角计算
+func TextWidth(s string, size float64) float64 {
+	var w float64
+	for _, r := range s {
+		if r < 0x80 {
+			w += size / 2
+		} else {
+			w += size
+		}
+	}
+	return w
+}
+
+// WriteTo 输出完整的 PDF 文件
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{width: d.width}}
+	}
+
+	// 对象编号: 1 目录 2 页面树 3-5 字体，之后每页依次为页面和内容流
+	const firstPageObj = 6
+	var objects []string
+
+	kids := new(bytes.Buffer)
+	for i := range pages {
+		fmt.Fprintf(kids, "%d 0 R ", firstPageObj+i*2)
+	}
+
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(pages)),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light"+
+			" /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >>"+
+			" /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880]"+
+			" /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	)
+	for i, p := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				num(d.width), num(d.height), firstPageObj+i*2+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.buf.Len(), p.buf.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// encode 将文本编码为 UCS-2 大端十六进制串，基本平面以外的字符替换为问号
+func encode(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// num 格式化坐标等数值，去掉多余的小数位
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
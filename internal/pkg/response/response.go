@@ -57,6 +57,15 @@ func Fail(c *gin.Context, httpCode int, code int, message string) {
 	})
 }
 
+// FailWithData 带数据的失败响应（如字段级校验错误）
+func FailWithData(c *gin.Context, httpCode int, code int, message string, data any) {
+	c.JSON(httpCode, Response{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // BadRequest 参数错误
 func BadRequest(c *gin.Context, message string) {
 	Fail(c, http.StatusBadRequest, 400, message)
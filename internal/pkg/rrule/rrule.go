@@ -0,0 +1,331 @@
+// Package rrule 实现 RFC 5545 RRULE 的常用子集，用于生成周期性活动的发生时间
+//
+// 支持的属性:
+//
+//	FREQ=DAILY|WEEKLY|MONTHLY（必填）
+//	INTERVAL=n
+//	COUNT=n 或 UNTIL=20060102T150405Z（二者必填其一，不带 Z 的本地时间和纯日期按 DTSTART 的时区解释）
+//	BYDAY=MO,TU,...（MONTHLY 时可带序号，如 1SA、-1SU）
+//	BYMONTHDAY=1,15,-1（仅 MONTHLY，与 BYDAY 同时指定时取交集）
+//
+// 周起始日固定为周一（WKST=MO），发生时间保留 DTSTART 的时分秒和时区
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq 重复频率
+type Freq string
+
+const (
+	Daily   Freq = "DAILY"
+	Weekly  Freq = "WEEKLY"
+	Monthly Freq = "MONTHLY"
+)
+
+// maxPeriods 展开时最多遍历的周期数，防止规则无法命中时死循环
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ByDay BYDAY 中的一项，N 为 0 表示不限序号
+type ByDay struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule 解析后的重复规则
+type Rule struct {
+	Freq       Freq
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []ByDay
+	ByMonthDay []int
+
+	untilFloating bool // UNTIL 不带 Z 后缀，Until 为 DTSTART 时区下的墙上时间
+}
+
+// Parse 解析 RRULE 字符串，可带或不带 "RRULE:" 前缀
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("重复规则为空")
+	}
+
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("无效的规则片段: %s", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			switch f := Freq(strings.ToUpper(value)); f {
+			case Daily, Weekly, Monthly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("不支持的 FREQ: %s", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("无效的 INTERVAL: %s", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("无效的 COUNT: %s", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, floating, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &t
+			r.untilFloating = floating
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				d, err := parseByDay(v)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, d)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("无效的 BYMONTHDAY: %s", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, errors.New("仅支持 WKST=MO")
+			}
+		default:
+			return nil, fmt.Errorf("不支持的规则属性: %s", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("缺少 FREQ")
+	}
+	if r.Count == 0 && r.Until == nil {
+		return nil, errors.New("COUNT 和 UNTIL 必须指定其一")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("COUNT 和 UNTIL 不能同时指定")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY 仅支持 FREQ=MONTHLY")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return nil, errors.New("带序号的 BYDAY 仅支持 FREQ=MONTHLY")
+		}
+	}
+	return r, nil
+}
+
+// parseUntil 解析 UNTIL，支持 UTC 时间（带 Z）、本地时间和纯日期
+// 本地时间和纯日期为浮动时间（floating 为 true），展开时按 DTSTART 的时区解释
+func parseUntil(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", v); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102", v); err == nil {
+		// 纯日期包含当天全天
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("无效的 UNTIL: %s", v)
+}
+
+// until 返回 UNTIL 对应的时刻，浮动时间按 loc 解释
+func (r *Rule) until(loc *time.Location) *time.Time {
+	if r.Until == nil || !r.untilFloating {
+		return r.Until
+	}
+	u := r.Until
+	t := time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, loc)
+	return &t
+}
+
+// parseByDay 解析 BYDAY 项，如 SA、1SA、-1SU
+func parseByDay(v string) (ByDay, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if len(v) < 2 {
+		return ByDay{}, fmt.Errorf("无效的 BYDAY: %s", v)
+	}
+	wd, ok := weekdays[v[len(v)-2:]]
+	if !ok {
+		return ByDay{}, fmt.Errorf("无效的 BYDAY: %s", v)
+	}
+	d := ByDay{Weekday: wd}
+	if prefix := v[:len(v)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return ByDay{}, fmt.Errorf("无效的 BYDAY: %s", v)
+		}
+		d.N = n
+	}
+	return d, nil
+}
+
+// All 从 dtstart 开始展开全部发生时间，超过 limit 个时返回错误
+func (r *Rule) All(dtstart time.Time, limit int) ([]time.Time, error) {
+	var out []time.Time
+	until := r.until(dtstart.Location())
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.candidates(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if until != nil && t.After(*until) {
+				return out, nil
+			}
+			if len(out) >= limit {
+				return nil, fmt.Errorf("重复规则生成的次数超过上限 %d", limit)
+			}
+			out = append(out, t)
+			if r.Count > 0 && len(out) >= r.Count {
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
+
+// candidates 返回第 period 个周期内按时间排序的候选时间
+func (r *Rule) candidates(dtstart time.Time, period int) []time.Time {
+	y, m, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+period*r.Interval)
+		if r.matchWeekday(t.Weekday()) {
+			out = append(out, t)
+		}
+
+	case Weekly:
+		// 以周一为周起始
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := d - offset + period*r.Interval*7
+		if len(r.ByDay) == 0 {
+			out = append(out, at(y, m, monday+offset))
+			break
+		}
+		for _, bd := range r.ByDay {
+			out = append(out, at(y, m, monday+(int(bd.Weekday)+6)%7))
+		}
+
+	case Monthly:
+		first := at(y, m+time.Month(period*r.Interval), 1)
+		fy, fm, _ := first.Date()
+		days := time.Date(fy, fm+1, 0, 0, 0, 0, 0, loc).Day()
+
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			// 当月没有 DTSTART 对应日期时跳过（如 31 日）
+			if d <= days {
+				out = append(out, at(fy, fm, d))
+			}
+			break
+		}
+		// 同时指定 BYMONTHDAY 和 BYDAY 时取交集（如 BYDAY=FR;BYMONTHDAY=13 为 13 日且是周五）
+		var byMonthDay, byDay map[int]bool
+		if len(r.ByMonthDay) > 0 {
+			byMonthDay = make(map[int]bool, len(r.ByMonthDay))
+			for _, md := range r.ByMonthDay {
+				if md < 0 {
+					md = days + md + 1
+				}
+				byMonthDay[md] = true
+			}
+		}
+		if len(r.ByDay) > 0 {
+			byDay = make(map[int]bool)
+			for _, bd := range r.ByDay {
+				for _, day := range monthWeekdays(fy, fm, days, loc, bd) {
+					byDay[day] = true
+				}
+			}
+		}
+		for day := 1; day <= days; day++ {
+			if (byMonthDay == nil || byMonthDay[day]) && (byDay == nil || byDay[day]) {
+				out = append(out, at(fy, fm, day))
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return dedupe(out)
+}
+
+// matchWeekday DAILY 规则下按 BYDAY 过滤
+func (r *Rule) matchWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, bd := range r.ByDay {
+		if bd.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// monthWeekdays 返回某月中匹配 BYDAY 的日期（带序号时只返回对应的一天）
+func monthWeekdays(y int, m time.Month, days int, loc *time.Location, bd ByDay) []int {
+	var all []int
+	firstWd := time.Date(y, m, 1, 0, 0, 0, 0, loc).Weekday()
+	for day := 1 + (int(bd.Weekday)-int(firstWd)+7)%7; day <= days; day += 7 {
+		all = append(all, day)
+	}
+
+	switch {
+	case bd.N == 0:
+		return all
+	case bd.N > 0 && bd.N <= len(all):
+		return all[bd.N-1 : bd.N]
+	case bd.N < 0 && -bd.N <= len(all):
+		return all[len(all)+bd.N : len(all)+bd.N+1]
+	}
+	return nil
+}
+
+func dedupe(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// cst 东八区，测试浮动 UNTIL 按 DTSTART 时区解释
+var cst = time.FixedZone("CST", 8*3600)
+
+// dates 将 "2006-01-02" 列表转为 cst 时区 09:00 的时间
+func dates(days ...string) []time.Time {
+	out := make([]time.Time, len(days))
+	for i, d := range days {
+		t, err := time.ParseInLocation("2006-01-02 15:04", d+" 09:00", cst)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = t
+	}
+	return out
+}
+
+func TestAll(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    string
+		dtstart string
+		want    []time.Time
+	}{
+		{"每天", "FREQ=DAILY;COUNT=3", "2026-01-30", dates("2026-01-30", "2026-01-31", "2026-02-01")},
+		{"每天按星期过滤", "FREQ=DAILY;BYDAY=SA,SU;COUNT=3", "2026-01-01", dates("2026-01-03", "2026-01-04", "2026-01-10")},
+		{"每周二四", "RRULE:FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4", "2026-01-05", dates("2026-01-06", "2026-01-08", "2026-01-13", "2026-01-15")},
+		{"隔周至日期（含当天）", "FREQ=WEEKLY;INTERVAL=2;UNTIL=20260202", "2026-01-05", dates("2026-01-05", "2026-01-19", "2026-02-02")},
+		{"每月同日跳过无此日的月份", "FREQ=MONTHLY;COUNT=3", "2026-01-31", dates("2026-01-31", "2026-03-31", "2026-05-31")},
+		{"每月多个日期", "FREQ=MONTHLY;BYMONTHDAY=1,15;COUNT=3", "2026-01-01", dates("2026-01-01", "2026-01-15", "2026-02-01")},
+		{"每月最后一天", "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "2026-01-10", dates("2026-01-31", "2026-02-28", "2026-03-31")},
+		{"每月第一个周六", "FREQ=MONTHLY;BYDAY=1SA;COUNT=3", "2026-01-01", dates("2026-01-03", "2026-02-07", "2026-03-07")},
+		{"每月最后一个周日", "FREQ=MONTHLY;BYDAY=-1SU;COUNT=3", "2026-01-01", dates("2026-01-25", "2026-02-22", "2026-03-29")},
+		{"隔月第一个周六", "FREQ=MONTHLY;INTERVAL=2;BYDAY=1SA;COUNT=3", "2026-01-01", dates("2026-01-03", "2026-03-07", "2026-05-02")},
+		{"BYDAY 与 BYMONTHDAY 取交集", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=4", "2026-01-01", dates("2026-02-13", "2026-03-13", "2026-11-13", "2027-08-13")},
+		{"浮动 UNTIL 按 DTSTART 时区", "FREQ=DAILY;UNTIL=20260304T080000", "2026-03-02", dates("2026-03-02", "2026-03-03")},
+		{"UTC UNTIL", "FREQ=DAILY;UNTIL=20260304T010000Z", "2026-03-02", dates("2026-03-02", "2026-03-03", "2026-03-04")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.rule, err)
+			}
+			got, err := r.All(dates(tc.dtstart)[0], 100)
+			if err != nil {
+				t.Fatalf("All: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tc.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tc.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAllLimit(t *testing.T) {
+	r, err := Parse("FREQ=DAILY;COUNT=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.All(dates("2026-01-01")[0], 5); err == nil {
+		t.Error("All should fail when occurrences exceed the limit")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	cases := []struct {
+		rule string
+		want string
+	}{
+		{"", "为空"},
+		{"COUNT=3", "缺少 FREQ"},
+		{"FREQ=YEARLY;COUNT=3", "不支持的 FREQ"},
+		{"FREQ=DAILY", "必须指定其一"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20260101", "不能同时指定"},
+		{"FREQ=DAILY;INTERVAL=0;COUNT=3", "INTERVAL"},
+		{"FREQ=DAILY;UNTIL=2026-01-01", "UNTIL"},
+		{"FREQ=WEEKLY;BYMONTHDAY=1;COUNT=3", "BYMONTHDAY 仅支持"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32;COUNT=3", "BYMONTHDAY"},
+		{"FREQ=WEEKLY;BYDAY=1MO;COUNT=3", "带序号的 BYDAY"},
+		{"FREQ=MONTHLY;BYDAY=6SA;COUNT=3", "BYDAY"},
+		{"FREQ=WEEKLY;WKST=SU;COUNT=3", "WKST"},
+		{"FREQ=DAILY;BYHOUR=9;COUNT=3", "不支持的规则属性"},
+	}
+	for _, tc := range cases {
+		_, err := Parse(tc.rule)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%q) error = %v, want containing %q", tc.rule, err, tc.want)
+		}
+	}
+}
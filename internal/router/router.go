@@ -2,6 +2,7 @@ package router
 
 import (
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,8 +13,8 @@ import (
 	"github.com/zzhtl/go-mountain/internal/service"
 )
 
-// Setup 配置所有路由
-func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
+// Setup 配置所有路由，返回需由服务器定时运行的对账服务
+func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config, systemConfigSvc *service.SystemConfigService) *service.ReconcileService {
 	// 全局中间件
 	engine.Use(middleware.CORS())
 
@@ -40,10 +41,20 @@ func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	menuSvc := service.NewMenuService(db)
 	userSvc := service.NewUserService(db, cfg.Wechat.AppID, cfg.Wechat.Secret)
 	activitySvc := service.NewActivityService(db)
-	registrationSvc := service.NewRegistrationService(db)
-	systemConfigSvc := service.NewSystemConfigService(db)
+	activityTicketSvc := service.NewActivityTicketService(db)
+	activityTemplateSvc := service.NewActivityTemplateService(db)
+	activityReviewSvc := service.NewActivityReviewService(db)
 	paymentSvc := service.NewPaymentService(db, systemConfigSvc)
+	reconcileSvc := service.NewReconcileService(db, paymentSvc)
+	reportSvc := service.NewReportService(db)
+	registrationSvc := service.NewRegistrationService(db, paymentSvc)
+	registrationOrderSvc := service.NewRegistrationOrderService(db, paymentSvc)
+	checkinSvc := service.NewCheckinService(db, cfg.JWT.Secret)
 	codegenSvc := service.NewCodegenService(db)
+	operationLogSvc := service.NewOperationLogService(db)
+	calendarSvc := service.NewCalendarService(db, activitySvc)
+	volunteerHourSvc := service.NewVolunteerHourService(db, systemConfigSvc)
+	donationSvc := service.NewDonationService(db, paymentSvc, systemConfigSvc)
 
 	// 创建 handlers
 	authHandler := handler.NewAuthHandler(authSvc)
@@ -54,9 +65,18 @@ func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	menuHandler := handler.NewMenuHandler(menuSvc)
 	userHandler := handler.NewUserHandler(userSvc)
 	uploadHandler := handler.NewUploadHandler()
-	activityHandler := handler.NewActivityHandler(activitySvc)
-	registrationHandler := handler.NewRegistrationHandler(registrationSvc)
+	activityHandler := handler.NewActivityHandler(activitySvc, activityTicketSvc)
+	activityTemplateHandler := handler.NewActivityTemplateHandler(activityTemplateSvc)
+	activityReviewHandler := handler.NewActivityReviewHandler(activityReviewSvc)
+	registrationHandler := handler.NewRegistrationHandler(registrationSvc, operationLogSvc)
+	registrationOrderHandler := handler.NewRegistrationOrderHandler(registrationOrderSvc)
+	checkinHandler := handler.NewCheckinHandler(checkinSvc)
+	calendarHandler := handler.NewCalendarHandler(calendarSvc)
+	volunteerHourHandler := handler.NewVolunteerHourHandler(volunteerHourSvc)
+	donationHandler := handler.NewDonationHandler(donationSvc)
 	paymentHandler := handler.NewPaymentHandler(paymentSvc)
+	reconcileHandler := handler.NewReconcileHandler(reconcileSvc)
+	reportHandler := handler.NewReportHandler(reportSvc)
 	systemConfigHandler := handler.NewSystemConfigHandler(systemConfigSvc)
 	codegenHandler := handler.NewCodegenHandler(codegenSvc)
 
@@ -76,25 +96,70 @@ func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		// 活动（公开）
 		mpActivities := mp.Group("/activities")
 		mpActivities.GET("/", activityHandler.ListForMP)
+		mpActivities.GET("/nearby", activityHandler.Nearby)
 		mpActivities.GET("/:id", activityHandler.GetForMP)
+		mpActivities.GET("/:id/reviews", activityReviewHandler.ListForMP)
+
+		// 日历订阅（公开活动日历；个人日历凭订阅令牌访问）
+		mp.GET("/calendar/activities.ics", calendarHandler.PublicFeed)
+		mp.GET("/calendar/feeds/:token", calendarHandler.UserFeed)
+
+		// 募捐项目
+		mpCampaigns := mp.Group("/donation-campaigns")
+		mpCampaigns.GET("/", donationHandler.ListForMP)
+		mpCampaigns.GET("/:id", donationHandler.GetForMP)
+		mpCampaigns.GET("/:id/donors", donationHandler.Donors)
+
+		// 志愿服务证明核验
+		mp.GET("/volunteer-certificates/:code", volunteerHourHandler.Verify)
 
 		// 需要小程序用户认证的接口
 		mpAuth := mp.Group("")
 		mpAuth.Use(middleware.JWTAuth(cfg.JWT.Secret))
+		mpAuth.Use(middleware.Idempotency(24 * time.Hour))
 		{
 			// 报名
 			mpAuth.POST("/registrations", registrationHandler.Create)
 			mpAuth.PUT("/registrations/:id/cancel", registrationHandler.Cancel)
+			mpAuth.GET("/registrations/:id/refund-preview", registrationHandler.RefundPreview)
 			mpAuth.GET("/registrations/mine", registrationHandler.MyRegistrations)
+			mpAuth.GET("/registrations/:id/checkin-token", checkinHandler.GetToken)
+
+			// 活动评价
+			mpAuth.POST("/activity-reviews", activityReviewHandler.Create)
+			mpAuth.POST("/upload/image", uploadHandler.UploadImage)
+
+			// 个人日历订阅地址
+			mpAuth.GET("/calendar/token", calendarHandler.GetToken)
+			mpAuth.POST("/calendar/token/reset", calendarHandler.ResetToken)
+
+			// 志愿时长
+			mpAuth.GET("/volunteer-hours/mine", volunteerHourHandler.Mine)
+			mpAuth.GET("/volunteer-hours/certificate", volunteerHourHandler.Certificate)
+
+			// 捐赠
+			mpAuth.POST("/donation-campaigns/:id/donate", donationHandler.Donate)
+			mpAuth.GET("/donations/mine", donationHandler.MyDonations)
+			mpAuth.GET("/donations/:id/receipt", donationHandler.MyReceipt)
+
+			// 团体报名
+			mpAuth.POST("/registration-orders", registrationOrderHandler.Create)
+			mpAuth.GET("/registration-orders/mine", registrationOrderHandler.MyOrders)
+			mpAuth.GET("/registration-orders/:id", registrationOrderHandler.Get)
+			mpAuth.PUT("/registration-orders/:id/cancel", registrationOrderHandler.Cancel)
 
 			// 支付
 			mpAuth.POST("/payments/create", paymentHandler.CreateOrder)
 			mpAuth.GET("/payments/query", paymentHandler.QueryOrder)
+			mpAuth.GET("/payments/qrcode", paymentHandler.QRCode)
 		}
 	}
 
-	// 微信支付回调（不需要任何认证）
+	// 支付、退款结果回调（不需要任何认证）
 	api.POST("/payment/wechat/notify", paymentHandler.WechatNotify)
+	api.POST("/payment/wechat/refund-notify", paymentHandler.WechatRefundNotify)
+	api.POST("/payment/mock/notify", paymentHandler.MockNotify)
+	api.POST("/payment/mock/refund-notify", paymentHandler.MockRefundNotify)
 
 	// ==================== 后台 API ====================
 	admin := api.Group("/admin")
@@ -177,22 +242,91 @@ func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		activities.PUT("/:id", activityHandler.Update)
 		activities.DELETE("/:id", activityHandler.Delete)
 		activities.PUT("/:id/status", activityHandler.UpdateStatus)
+		activities.GET("/:id/tickets", activityHandler.ListTickets)
+		activities.PUT("/:id/tickets", activityHandler.SaveTickets)
+		activities.POST("/:id/checkin", checkinHandler.Scan)
+		activities.GET("/:id/attendance", checkinHandler.Report)
+		activities.POST("/:id/clone", activityHandler.Clone)
+
+		// 活动评价
+		activityReviews := adminAuth.Group("/activity-reviews")
+		activityReviews.GET("/", activityReviewHandler.List)
+		activityReviews.PUT("/:id/status", activityReviewHandler.UpdateStatus)
+		activityReviews.PUT("/:id/featured", activityReviewHandler.SetFeatured)
+
+		// 活动模板
+		activityTemplates := adminAuth.Group("/activity-templates")
+		activityTemplates.GET("/", activityTemplateHandler.List)
+		activityTemplates.POST("/", activityTemplateHandler.Create)
+		activityTemplates.GET("/:id", activityTemplateHandler.Get)
+		activityTemplates.PUT("/:id", activityTemplateHandler.Update)
+		activityTemplates.DELETE("/:id", activityTemplateHandler.Delete)
+		activityTemplates.POST("/:id/generate", activityTemplateHandler.Generate)
+
+		// 志愿时长
+		volunteerHours := adminAuth.Group("/volunteer-hours")
+		volunteerHours.GET("/", volunteerHourHandler.List)
+		volunteerHours.POST("/", volunteerHourHandler.Create)
+		volunteerHours.GET("/totals", volunteerHourHandler.Totals)
+		volunteerHours.POST("/settle", volunteerHourHandler.Settle)
+		volunteerHours.DELETE("/:id", volunteerHourHandler.Delete)
 
 		// 报名管理
 		registrations := adminAuth.Group("/registrations")
 		registrations.GET("/", registrationHandler.List)
+		registrations.GET("/export", registrationHandler.Export)
+		registrations.GET("/export-columns", registrationHandler.ExportColumns)
+		registrations.POST("/", registrationHandler.AdminCreate)
 		registrations.GET("/:id", registrationHandler.Get)
+		registrations.PUT("/:id", registrationHandler.Update)
+		registrations.PUT("/:id/cancel", registrationHandler.AdminCancel)
+		registrations.PUT("/:id/paid", registrationHandler.MarkPaid)
+		registrations.PUT("/:id/approve", registrationHandler.Approve)
+		registrations.PUT("/:id/reject", registrationHandler.Reject)
+
+		// 募捐管理
+		campaigns := adminAuth.Group("/donation-campaigns")
+		campaigns.GET("/", donationHandler.List)
+		campaigns.POST("/", donationHandler.Create)
+		campaigns.GET("/:id", donationHandler.Get)
+		campaigns.PUT("/:id", donationHandler.Update)
+		campaigns.DELETE("/:id", donationHandler.Delete)
+		campaigns.PUT("/:id/status", donationHandler.UpdateStatus)
+
+		donations := adminAuth.Group("/donations")
+		donations.GET("/", donationHandler.ListDonations)
+
+		receipts := adminAuth.Group("/donation-receipts")
+		receipts.GET("/", donationHandler.ListReceipts)
+		receipts.GET("/:id/pdf", donationHandler.ReceiptPDF)
+		receipts.PUT("/:id/void", donationHandler.VoidReceipt)
 
 		// 支付管理
 		payments := adminAuth.Group("/payments")
 		payments.GET("/", paymentHandler.List)
+		payments.GET("/refunds", paymentHandler.ListRefunds)
 		payments.GET("/:id", paymentHandler.Get)
 		payments.PUT("/:id/refund", paymentHandler.Refund)
 
+		// 支付对账
+		reconciliations := adminAuth.Group("/payment-reconciliations")
+		reconciliations.GET("/", reconcileHandler.List)
+		reconciliations.GET("/:id", reconcileHandler.Get)
+		reconciliations.POST("/", reconcileHandler.Run)
+
+		// 财务报表
+		reports := adminAuth.Group("/financial-reports")
+		reports.GET("/summary", reportHandler.Summary)
+		reports.GET("/revenue", reportHandler.Revenue)
+		reports.GET("/biz-types", reportHandler.ByBizType)
+		reports.GET("/activities", reportHandler.ByActivity)
+		reports.GET("/export", reportHandler.Export)
+
 		// 系统配置管理
 		sysConfigs := adminAuth.Group("/system-configs")
 		sysConfigs.GET("/", systemConfigHandler.List)
 		sysConfigs.GET("/groups", systemConfigHandler.GetGroups)
+		sysConfigs.GET("/schema", systemConfigHandler.Schema)
 		sysConfigs.POST("/", systemConfigHandler.Save)
 		sysConfigs.POST("/batch", systemConfigHandler.BatchSave)
 		sysConfigs.DELETE("/", systemConfigHandler.Delete)
@@ -231,4 +365,6 @@ func Setup(engine *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	// 重定向
 	engine.GET("/admin", func(c *gin.Context) { c.Redirect(302, "/web/") })
 	engine.GET("/admin/", func(c *gin.Context) { c.Redirect(302, "/web/") })
+
+	return reconcileSvc
 }
@@ -14,24 +14,27 @@ import (
 
 	"github.com/zzhtl/go-mountain/internal/config"
 	"github.com/zzhtl/go-mountain/internal/router"
+	"github.com/zzhtl/go-mountain/internal/service"
 )
 
 // Server 封装 HTTP 服务器
 type Server struct {
-	engine *gin.Engine
-	db     *gorm.DB
-	cfg    *config.Config
+	engine       *gin.Engine
+	db           *gorm.DB
+	cfg          *config.Config
+	reconcileSvc *service.ReconcileService
 }
 
-// NewServer 创建服务器实例
-func NewServer(db *gorm.DB, cfg *config.Config) *Server {
+// NewServer 创建服务器实例，systemConfigSvc 为启动时已初始化的系统配置服务
+func NewServer(db *gorm.DB, cfg *config.Config, systemConfigSvc *service.SystemConfigService) *Server {
 	engine := gin.Default()
-	router.Setup(engine, db, cfg)
+	reconcileSvc := router.Setup(engine, db, cfg, systemConfigSvc)
 
 	return &Server{
-		engine: engine,
-		db:     db,
-		cfg:    cfg,
+		engine:       engine,
+		db:           db,
+		cfg:          cfg,
+		reconcileSvc: reconcileSvc,
 	}
 }
 
@@ -56,6 +59,9 @@ func (s *Server) Run() error {
 		}
 	}()
 
+	// 启动支付对账定时任务
+	go s.runReconcile(ctx)
+
 	// 等待关闭信号
 	<-ctx.Done()
 	log.Println("收到关闭信号，正在优雅关闭...")
@@ -76,3 +82,23 @@ func (s *Server) Run() error {
 	log.Println("服务器已关闭")
 	return nil
 }
+
+// reconcileInterval 支付对账定时任务的执行间隔
+const reconcileInterval = 5 * time.Minute
+
+// runReconcile 定时同步待支付订单并对前一日微信账单，服务器关闭时退出
+func (s *Server) runReconcile(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reconcileSvc.RunScheduled(ctx); err != nil {
+				log.Printf("支付对账任务失败: %v", err)
+			}
+		}
+	}
+}
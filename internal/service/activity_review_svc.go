@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// ActivityReviewService 活动评价服务
+type ActivityReviewService struct {
+	repo *repository.BaseRepo[model.ActivityReview]
+	db   *gorm.DB
+}
+
+// NewActivityReviewService 创建活动评价服务
+func NewActivityReviewService(db *gorm.DB) *ActivityReviewService {
+	return &ActivityReviewService{
+		repo: repository.NewBaseRepo[model.ActivityReview](db),
+		db:   db,
+	}
+}
+
+// CreateReviewRequest 提交评价请求
+type CreateReviewRequest struct {
+	RegistrationID int64    `json:"registration_id" binding:"required"`
+	Rating         int      `json:"rating" binding:"required,min=1,max=5"`
+	Content        string   `json:"content" binding:"max=1000"`
+	Images         []string `json:"images" binding:"max=9,dive,required,max=500"`
+}
+
+// Create 提交活动评价（小程序端）
+// 仅本人已确认的报名可在活动结束后评价，每条报名只能评价一次
+func (s *ActivityReviewService) Create(ctx context.Context, userID int64, req *CreateReviewRequest) (*model.ActivityReview, error) {
+	var reg model.Registration
+	if err := s.db.WithContext(ctx).Preload("Activity").First(&reg, req.RegistrationID).Error; err != nil {
+		return nil, errcode.ErrRegistrationNotFound
+	}
+
+	if reg.UserID != userID {
+		return nil, errcode.ErrForbidden
+	}
+
+	if reg.Status != 1 || reg.Activity == nil {
+		return nil, errcode.ErrReviewNotAllowed
+	}
+	if reg.Activity.Status != 4 && time.Now().Before(reg.Activity.EndTime) {
+		return nil, errcode.ErrReviewNotAllowed
+	}
+
+	exists, _ := s.repo.Exists(ctx, "registration_id = ?", reg.ID)
+	if exists {
+		return nil, errcode.ErrAlreadyReviewed
+	}
+
+	review := &model.ActivityReview{
+		ActivityID:     reg.ActivityID,
+		RegistrationID: reg.ID,
+		UserID:         userID,
+		Rating:         req.Rating,
+		Content:        req.Content,
+		Status:         1,
+	}
+	if len(req.Images) > 0 {
+		review.Images, _ = json.Marshal(req.Images)
+	}
+
+	// 唯一索引兜底并发重复提交
+	if err := s.repo.Create(ctx, review); err != nil {
+		return nil, errcode.ErrAlreadyReviewed
+	}
+	return review, nil
+}
+
+// ReviewListItem 评价列表项（含评价人昵称、头像）
+type ReviewListItem struct {
+	model.ActivityReview
+	UserName      string `json:"user_name"`
+	UserAvatar    string `json:"user_avatar"`
+	ActivityTitle string `json:"activity_title,omitempty"`
+}
+
+// ListForMP 获取活动的公开评价（小程序端），精选评价置顶
+func (s *ActivityReviewService) ListForMP(ctx context.Context, activityID int64, page, pageSize int) ([]ReviewListItem, int64, error) {
+	var (
+		list  []ReviewListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("activity_reviews").
+		Select("activity_reviews.*, users.name as user_name, users.avatar as user_avatar").
+		Joins("LEFT JOIN users ON activity_reviews.user_id = users.id").
+		Where("activity_reviews.activity_id = ? AND activity_reviews.status = 1 AND activity_reviews.deleted_at IS NULL", activityID)
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("activity_reviews.featured DESC, activity_reviews.created_at DESC").
+		Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// List 获取评价列表（后台管理），status 为 -1 时不过滤
+func (s *ActivityReviewService) List(ctx context.Context, page, pageSize int, activityID int64, status int) ([]ReviewListItem, int64, error) {
+	var (
+		list  []ReviewListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("activity_reviews").
+		Select("activity_reviews.*, users.name as user_name, users.avatar as user_avatar, activities.title as activity_title").
+		Joins("LEFT JOIN users ON activity_reviews.user_id = users.id").
+		Joins("LEFT JOIN activities ON activity_reviews.activity_id = activities.id").
+		Where("activity_reviews.deleted_at IS NULL")
+
+	if activityID > 0 {
+		db = db.Where("activity_reviews.activity_id = ?", activityID)
+	}
+	if status >= 0 {
+		db = db.Where("activity_reviews.status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("activity_reviews.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// UpdateStatus 显示或隐藏评价（后台审核）
+func (s *ActivityReviewService) UpdateStatus(ctx context.Context, id int64, status int) error {
+	return s.update(ctx, id, map[string]any{"status": status})
+}
+
+// SetFeatured 设置或取消精选评价（后台）
+func (s *ActivityReviewService) SetFeatured(ctx context.Context, id int64, featured bool) error {
+	return s.update(ctx, id, map[string]any{"featured": featured})
+}
+
+func (s *ActivityReviewService) update(ctx context.Context, id int64, updates map[string]any) error {
+	res := s.db.WithContext(ctx).Model(&model.ActivityReview{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errcode.ErrReviewNotFound
+	}
+	return nil
+}
@@ -2,34 +2,49 @@ package service
 
 import (
 	"context"
+	"math"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/zzhtl/go-mountain/internal/model"
 	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/geo"
 	"github.com/zzhtl/go-mountain/internal/repository"
 )
 
 // ActivityService 活动管理服务
 type ActivityService struct {
-	repo *repository.BaseRepo[model.Activity]
-	db   *gorm.DB
+	repo      *repository.BaseRepo[model.Activity]
+	db        *gorm.DB
+	ticketSvc *ActivityTicketService
 }
 
 // NewActivityService 创建活动服务
 func NewActivityService(db *gorm.DB) *ActivityService {
 	return &ActivityService{
-		repo: repository.NewBaseRepo[model.Activity](db),
-		db:   db,
+		repo:      repository.NewBaseRepo[model.Activity](db),
+		db:        db,
+		ticketSvc: NewActivityTicketService(db),
 	}
 }
 
-// ActivityListItem 活动列表项（含报名人数）
+// ActivityListItem 活动列表项（含报名人数和评价汇总）
 type ActivityListItem struct {
 	model.Activity
-	RegCount int64 `json:"reg_count"`
+	RegCount    int64            `json:"reg_count"`
+	RatingAvg   float64          `json:"rating_avg"`   // 公开评价的平均分，保留一位小数
+	RatingCount int64            `json:"rating_count"` // 公开评价数
+	Tickets     []TicketListItem `gorm:"-" json:"tickets,omitempty"`
 }
 
+// activityListSelect 活动列表查询字段（报名人数、评价汇总以子查询统计）
+const activityListSelect = "activities.*, " +
+	"(SELECT COUNT(*) FROM registrations WHERE registrations.activity_id = activities.id AND registrations.status IN (0,1,4) AND registrations.deleted_at IS NULL) as reg_count, " +
+	"(SELECT COALESCE(ROUND(AVG(activity_reviews.rating * 1.0), 1), 0) FROM activity_reviews WHERE activity_reviews.activity_id = activities.id AND activity_reviews.status = 1 AND activity_reviews.deleted_at IS NULL) as rating_avg, " +
+	"(SELECT COUNT(*) FROM activity_reviews WHERE activity_reviews.activity_id = activities.id AND activity_reviews.status = 1 AND activity_reviews.deleted_at IS NULL) as rating_count"
+
 // List 获取活动列表（后台管理）
 func (s *ActivityService) List(ctx context.Context, page, pageSize int, status int) ([]ActivityListItem, int64, error) {
 	var (
@@ -38,7 +53,7 @@ func (s *ActivityService) List(ctx context.Context, page, pageSize int, status i
 	)
 
 	db := s.db.WithContext(ctx).Table("activities").
-		Select("activities.*, (SELECT COUNT(*) FROM registrations WHERE registrations.activity_id = activities.id AND registrations.status IN (0,1) AND registrations.deleted_at IS NULL) as reg_count").
+		Select(activityListSelect).
 		Where("activities.deleted_at IS NULL")
 
 	if status >= 0 {
@@ -58,28 +73,172 @@ func (s *ActivityService) List(ctx context.Context, page, pageSize int, status i
 func (s *ActivityService) Get(ctx context.Context, id int64) (*ActivityListItem, error) {
 	var item ActivityListItem
 	err := s.db.WithContext(ctx).Table("activities").
-		Select("activities.*, (SELECT COUNT(*) FROM registrations WHERE registrations.activity_id = activities.id AND registrations.status IN (0,1) AND registrations.deleted_at IS NULL) as reg_count").
+		Select(activityListSelect).
 		Where("activities.id = ? AND activities.deleted_at IS NULL", id).
 		First(&item).Error
 	if err != nil {
 		return nil, errcode.ErrNotFound
 	}
+	item.Tickets, _ = s.ticketSvc.ListByActivity(ctx, id, false)
 	return &item, nil
 }
 
 // Create 创建活动
 func (s *ActivityService) Create(ctx context.Context, activity *model.Activity) error {
+	markPublished(activity)
 	return s.repo.Create(ctx, activity)
 }
 
-// Update 更新活动
+// Update 更新活动，同时递增日历订阅的 SEQUENCE
 func (s *ActivityService) Update(ctx context.Context, id int64, updates map[string]any) error {
+	updates["sequence"] = gorm.Expr("sequence + 1")
+	markPublishedUpdates(updates)
 	return s.repo.Update(ctx, id, updates)
 }
 
-// UpdateStatus 更新活动状态
+// markPublished 新建的活动不是草稿时记录发布时间
+func markPublished(activity *model.Activity) {
+	if activity.Status != 0 && activity.PublishedAt == nil {
+		now := time.Now()
+		activity.PublishedAt = &now
+	}
+}
+
+// markPublishedUpdates 状态改为非草稿时记录首次发布时间（已发布过的保持不变）
+func markPublishedUpdates(updates map[string]any) {
+	if status, ok := updates["status"].(int); ok && status != 0 {
+		updates["published_at"] = gorm.Expr("COALESCE(published_at, ?)", time.Now())
+	}
+}
+
+// UpdateFuture 更新周期活动的本期及之后各期
+// 时间字段按本期的修改量平移到各期：开始时间整体平移，结束时间和报名窗口保持与开始时间的相对间隔；
+// 状态字段不同步，各期保持各自状态。非系列活动等同于 Update。返回更新的期数
+func (s *ActivityService) UpdateFuture(ctx context.Context, id int64, updates map[string]any) (int, error) {
+	var current model.Activity
+	if err := s.db.WithContext(ctx).First(&current, id).Error; err != nil {
+		return 0, errcode.ErrNotFound
+	}
+	if current.SeriesID == nil {
+		return 1, s.Update(ctx, id, updates)
+	}
+
+	newStart, _ := updates["start_time"].(time.Time)
+	newEnd, _ := updates["end_time"].(time.Time)
+	regStart, _ := updates["reg_start_time"].(*time.Time)
+	regEnd, _ := updates["reg_end_time"].(*time.Time)
+	shift := newStart.Sub(current.StartTime)
+
+	var occurrences []model.Activity
+	err := s.db.WithContext(ctx).
+		Where("series_id = ? AND start_time >= ?", *current.SeriesID, current.StartTime).
+		Find(&occurrences).Error
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, occ := range occurrences {
+			start := occ.StartTime.Add(shift)
+			occUpdates := make(map[string]any, len(updates))
+			for k, v := range updates {
+				if k != "status" || occ.ID == id {
+					occUpdates[k] = v
+				}
+			}
+			occUpdates["start_time"] = start
+			occUpdates["end_time"] = start.Add(newEnd.Sub(newStart))
+			occUpdates["reg_start_time"] = relativeTo(start, newStart, regStart)
+			occUpdates["reg_end_time"] = relativeTo(start, newStart, regEnd)
+			occUpdates["sequence"] = gorm.Expr("sequence + 1")
+			markPublishedUpdates(occUpdates)
+
+			if err := tx.Model(&model.Activity{}).Where("id = ?", occ.ID).Updates(occUpdates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(occurrences), err
+}
+
+// relativeTo 按 t 相对 base 的间隔换算到新的开始时间 start，t 为空时返回 nil
+func relativeTo(start, base time.Time, t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := start.Add(t.Sub(base))
+	return &v
+}
+
+// CloneRequest 复制活动请求
+type CloneRequest struct {
+	Title     string     `json:"title"`      // 为空时沿用原标题
+	StartTime *time.Time `json:"start_time"` // 新活动开始时间，为空时沿用原时间；其余时间按相同间隔平移
+}
+
+// Clone 复制活动内容、票种和报名表单，新活动为草稿状态且不属于任何周期系列
+func (s *ActivityService) Clone(ctx context.Context, id, operatorID int64, req *CloneRequest) (*model.Activity, error) {
+	var src model.Activity
+	if err := s.db.WithContext(ctx).First(&src, id).Error; err != nil {
+		return nil, errcode.ErrNotFound
+	}
+
+	var shift time.Duration
+	if req.StartTime != nil {
+		shift = req.StartTime.Sub(src.StartTime)
+	}
+	shiftPtr := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		v := t.Add(shift)
+		return &v
+	}
+
+	activity := src
+	activity.BaseModel = model.BaseModel{}
+	activity.StartTime = src.StartTime.Add(shift)
+	activity.EndTime = src.EndTime.Add(shift)
+	activity.RegStartTime = shiftPtr(src.RegStartTime)
+	activity.RegEndTime = shiftPtr(src.RegEndTime)
+	activity.SeriesID = nil
+	activity.Sequence = 0
+	activity.Status = 0
+	activity.CreatedBy = operatorID
+	if req.Title != "" {
+		activity.Title = req.Title
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&activity).Error; err != nil {
+			return err
+		}
+
+		var tickets []model.ActivityTicket
+		if err := tx.Where("activity_id = ?", id).Order("sort, id").Find(&tickets).Error; err != nil {
+			return err
+		}
+		for _, t := range tickets {
+			t.BaseModel = model.BaseModel{}
+			t.ActivityID = activity.ID
+			t.SaleStartTime = shiftPtr(t.SaleStartTime)
+			t.SaleEndTime = shiftPtr(t.SaleEndTime)
+			if err := createTicket(tx, &t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
+
+// UpdateStatus 更新活动状态，同时递增日历订阅的 SEQUENCE
 func (s *ActivityService) UpdateStatus(ctx context.Context, id int64, status int) error {
-	return s.repo.Update(ctx, id, map[string]any{"status": status})
+	return s.Update(ctx, id, map[string]any{"status": status})
 }
 
 // Delete 删除活动
@@ -87,24 +246,32 @@ func (s *ActivityService) Delete(ctx context.Context, id int64) error {
 	// 检查是否有有效报名
 	var count int64
 	s.db.WithContext(ctx).Model(&model.Registration{}).
-		Where("activity_id = ? AND status IN (0,1) AND deleted_at IS NULL", id).
+		Where("activity_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", id).
 		Count(&count)
 	if count > 0 {
 		return errcode.ErrActivityHasRegistrations
 	}
-	return s.repo.Delete(ctx, id)
+	// 递增 SEQUENCE 后软删除，公开日历以更高的 SEQUENCE 输出取消事件
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Activity{}).Where("id = ?", id).
+			Update("sequence", gorm.Expr("sequence + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Activity{}, id).Error
+	})
 }
 
 // GetForMP 获取活动详情（小程序端）
 func (s *ActivityService) GetForMP(ctx context.Context, id int64) (*ActivityListItem, error) {
 	var item ActivityListItem
 	err := s.db.WithContext(ctx).Table("activities").
-		Select("activities.*, (SELECT COUNT(*) FROM registrations WHERE registrations.activity_id = activities.id AND registrations.status IN (0,1) AND registrations.deleted_at IS NULL) as reg_count").
+		Select(activityListSelect).
 		Where("activities.id = ? AND activities.status IN (1,2,3) AND activities.deleted_at IS NULL", id).
 		First(&item).Error
 	if err != nil {
 		return nil, errcode.ErrNotFound
 	}
+	item.Tickets, _ = s.ticketSvc.ListByActivity(ctx, id, true)
 	return &item, nil
 }
 
@@ -116,7 +283,7 @@ func (s *ActivityService) ListForMP(ctx context.Context, page, pageSize int) ([]
 	)
 
 	db := s.db.WithContext(ctx).Table("activities").
-		Select("activities.*, (SELECT COUNT(*) FROM registrations WHERE registrations.activity_id = activities.id AND registrations.status IN (0,1) AND registrations.deleted_at IS NULL) as reg_count").
+		Select(activityListSelect).
 		Where("activities.status IN (1,2,3,4) AND activities.deleted_at IS NULL")
 
 	if err := db.Count(&total).Error; err != nil {
@@ -127,3 +294,56 @@ func (s *ActivityService) ListForMP(ctx context.Context, page, pageSize int) ([]
 	err := db.Order("activities.start_time DESC").Offset(offset).Limit(pageSize).Find(&list).Error
 	return list, total, err
 }
+
+// NearbyActivity 附近活动（含距离）
+type NearbyActivity struct {
+	ActivityListItem
+	Distance float64 `json:"distance"` // 距用户位置的距离（千米）
+}
+
+// Nearby 查询用户附近的活动（小程序端），按距离由近到远排序
+// 先按外接矩形在数据库中粗筛，再在 Go 中计算球面距离精确过滤，不依赖数据库空间扩展
+func (s *ActivityService) Nearby(ctx context.Context, lat, lng, radiusKm float64, limit int) ([]NearbyActivity, error) {
+	db := s.db.WithContext(ctx).Table("activities").
+		Select(activityListSelect).
+		Where("activities.status IN (1,2,3) AND activities.deleted_at IS NULL").
+		Where("activities.latitude IS NOT NULL AND activities.longitude IS NOT NULL")
+
+	box := geo.BoundingBox(lat, lng, radiusKm)
+	db = db.Where("activities.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+	switch {
+	case box.AllLng:
+	case box.WrapLng:
+		db = db.Where("(activities.longitude >= ? OR activities.longitude <= ?)", box.MinLng, box.MaxLng)
+	default:
+		db = db.Where("activities.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+
+	var candidates []ActivityListItem
+	if err := db.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]NearbyActivity, 0, len(candidates))
+	for _, a := range candidates {
+		d := geo.Distance(lat, lng, *a.Latitude, *a.Longitude)
+		if d > radiusKm {
+			continue
+		}
+		list = append(list, NearbyActivity{
+			ActivityListItem: a,
+			Distance:         math.Round(d*100) / 100,
+		})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Distance != list[j].Distance {
+			return list[i].Distance < list[j].Distance
+		}
+		return list[i].StartTime.Before(list[j].StartTime)
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
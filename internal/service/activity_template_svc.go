@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/rrule"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// maxSeriesOccurrences 一次生成的周期活动期数上限
+const maxSeriesOccurrences = 100
+
+// ActivityTemplateService 活动模板服务
+type ActivityTemplateService struct {
+	repo *repository.BaseRepo[model.ActivityTemplate]
+	db   *gorm.DB
+}
+
+// NewActivityTemplateService 创建活动模板服务
+func NewActivityTemplateService(db *gorm.DB) *ActivityTemplateService {
+	return &ActivityTemplateService{
+		repo: repository.NewBaseRepo[model.ActivityTemplate](db),
+		db:   db,
+	}
+}
+
+// TemplateTicket 模板中的票种定义
+type TemplateTicket struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price" binding:"min=0"`
+	Quota       int         `json:"quota" binding:"min=0"`
+	Sort        int         `json:"sort"`
+}
+
+// List 获取活动模板列表
+func (s *ActivityTemplateService) List(ctx context.Context, page, pageSize int) ([]model.ActivityTemplate, int64, error) {
+	return s.repo.List(ctx, page, pageSize, func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	})
+}
+
+// Get 获取活动模板详情
+func (s *ActivityTemplateService) Get(ctx context.Context, id int64) (*model.ActivityTemplate, error) {
+	tpl, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errcode.ErrTemplateNotFound
+	}
+	return tpl, nil
+}
+
+// Create 创建活动模板
+func (s *ActivityTemplateService) Create(ctx context.Context, tpl *model.ActivityTemplate) error {
+	return s.repo.Create(ctx, tpl)
+}
+
+// Update 更新活动模板（已生成的活动不受影响）
+func (s *ActivityTemplateService) Update(ctx context.Context, id int64, updates map[string]any) error {
+	return s.repo.Update(ctx, id, updates)
+}
+
+// Delete 删除活动模板
+func (s *ActivityTemplateService) Delete(ctx context.Context, id int64) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// GenerateSeriesRequest 按重复规则生成周期活动请求
+type GenerateSeriesRequest struct {
+	RRule             string    `json:"rrule" binding:"required"`   // 如 FREQ=WEEKLY;BYDAY=SA;COUNT=8
+	DTStart           time.Time `json:"dtstart" binding:"required"` // 第一期开始时间（时分秒和时区用于后续各期）
+	RegOpenBeforeMin  *int      `json:"reg_open_before_min"`        // 开始前多少分钟开放报名
+	RegCloseBeforeMin *int      `json:"reg_close_before_min"`       // 开始前多少分钟截止报名
+	Status            int       `json:"status" binding:"oneof=0 1"` // 生成活动的初始状态：0 草稿 1 报名中
+}
+
+// Generate 按模板和重复规则生成周期活动系列
+// 每期活动复制模板内容、票种和报名表单，报名窗口按相对开始时间的偏移计算
+func (s *ActivityTemplateService) Generate(ctx context.Context, templateID, operatorID int64, req *GenerateSeriesRequest) (*model.ActivitySeries, []model.Activity, error) {
+	tpl, err := s.Get(ctx, templateID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tickets []TemplateTicket
+	if len(tpl.Tickets) > 0 {
+		if err := json.Unmarshal(tpl.Tickets, &tickets); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rule, err := rrule.Parse(req.RRule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errcode.ErrRecurrenceInvalid, err)
+	}
+	starts, err := rule.All(req.DTStart, maxSeriesOccurrences)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errcode.ErrRecurrenceInvalid, err)
+	}
+	if len(starts) == 0 {
+		return nil, nil, fmt.Errorf("%w: 规则未生成任何日期", errcode.ErrRecurrenceInvalid)
+	}
+
+	series := model.ActivitySeries{
+		TemplateID:        templateID,
+		RRule:             req.RRule,
+		DTStart:           req.DTStart,
+		RegOpenBeforeMin:  req.RegOpenBeforeMin,
+		RegCloseBeforeMin: req.RegCloseBeforeMin,
+		CreatedBy:         operatorID,
+	}
+	activities := make([]model.Activity, 0, len(starts))
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&series).Error; err != nil {
+			return err
+		}
+
+		for _, start := range starts {
+			activity := model.Activity{
+				GeoLocation:       tpl.GeoLocation,
+				Title:             tpl.Title,
+				Description:       tpl.Description,
+				Content:           tpl.Content,
+				Thumbnail:         tpl.Thumbnail,
+				Location:          tpl.Location,
+				StartTime:         start,
+				EndTime:           start.Add(time.Duration(tpl.DurationMinutes) * time.Minute),
+				RegStartTime:      offsetBefore(start, req.RegOpenBeforeMin),
+				RegEndTime:        offsetBefore(start, req.RegCloseBeforeMin),
+				MaxParticipants:   tpl.MaxParticipants,
+				Price:             tpl.Price,
+				RequiresApproval:  tpl.RequiresApproval,
+				RefundFullDays:    tpl.RefundFullDays,
+				RefundPartialDays: tpl.RefundPartialDays,
+				RefundPartialRate: tpl.RefundPartialRate,
+				FormSchema:        tpl.FormSchema,
+				SeriesID:          &series.ID,
+				Status:            req.Status,
+				CreatedBy:         operatorID,
+			}
+			markPublished(&activity)
+			if err := tx.Create(&activity).Error; err != nil {
+				return err
+			}
+
+			for _, t := range tickets {
+				if err := tx.Create(&model.ActivityTicket{
+					ActivityID:  activity.ID,
+					Name:        t.Name,
+					Description: t.Description,
+					Price:       t.Price,
+					Quota:       t.Quota,
+					Sort:        t.Sort,
+					Status:      1,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			activities = append(activities, activity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &series, activities, nil
+}
+
+// offsetBefore 计算开始时间前 minutes 分钟的时间，minutes 为空时返回 nil
+func offsetBefore(start time.Time, minutes *int) *time.Time {
+	if minutes == nil {
+		return nil
+	}
+	t := start.Add(-time.Duration(*minutes) * time.Minute)
+	return &t
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// ActivityTicketService 活动票种服务
+type ActivityTicketService struct {
+	repo *repository.BaseRepo[model.ActivityTicket]
+	db   *gorm.DB
+}
+
+// NewActivityTicketService 创建活动票种服务
+func NewActivityTicketService(db *gorm.DB) *ActivityTicketService {
+	return &ActivityTicketService{
+		repo: repository.NewBaseRepo[model.ActivityTicket](db),
+		db:   db,
+	}
+}
+
+// TicketListItem 票种列表项（含已售数量）
+type TicketListItem struct {
+	model.ActivityTicket
+	SoldCount int64 `json:"sold_count"`
+}
+
+// ListByActivity 获取活动的全部票种
+// onSaleOnly 为 true 时只返回在售票种（小程序端）
+func (s *ActivityTicketService) ListByActivity(ctx context.Context, activityID int64, onSaleOnly bool) ([]TicketListItem, error) {
+	var list []TicketListItem
+
+	db := s.db.WithContext(ctx).Table("activity_tickets").
+		Select("activity_tickets.*, (SELECT COUNT(*) FROM registrations WHERE registrations.ticket_id = activity_tickets.id AND registrations.status IN (0,1,4) AND registrations.deleted_at IS NULL) as sold_count").
+		Where("activity_tickets.activity_id = ? AND activity_tickets.deleted_at IS NULL", activityID)
+
+	if onSaleOnly {
+		db = db.Where("activity_tickets.status = 1")
+	}
+
+	err := db.Order("activity_tickets.sort, activity_tickets.id").Find(&list).Error
+	return list, err
+}
+
+// HasTickets 活动是否配置了票种
+func (s *ActivityTicketService) HasTickets(ctx context.Context, activityID int64) bool {
+	exists, _ := s.repo.Exists(ctx, "activity_id = ?", activityID)
+	return exists
+}
+
+// TicketRequest 保存票种请求（ID 为 0 表示新增）
+type TicketRequest struct {
+	ID            int64       `json:"id"`
+	Name          string      `json:"name" binding:"required"`
+	Description   string      `json:"description"`
+	Price         money.Money `json:"price" binding:"min=0"`
+	Quota         int         `json:"quota" binding:"min=0"`
+	SaleStartTime *time.Time  `json:"sale_start_time"`
+	SaleEndTime   *time.Time  `json:"sale_end_time"`
+	Sort          int         `json:"sort"`
+	Status        int         `json:"status" binding:"oneof=0 1"` // 0:停售 1:在售
+}
+
+// Save 全量保存活动票种：更新已有、新增缺失、删除未提交的票种
+func (s *ActivityTicketService) Save(ctx context.Context, activityID int64, reqs []TicketRequest) error {
+	for _, r := range reqs {
+		if r.SaleStartTime != nil && r.SaleEndTime != nil && r.SaleEndTime.Before(*r.SaleStartTime) {
+			return errcode.ErrTicketSaleTimeInvalid
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []model.ActivityTicket
+		if err := tx.Where("activity_id = ?", activityID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		kept := make(map[int64]bool, len(reqs))
+		for _, r := range reqs {
+			ticket := model.ActivityTicket{
+				ActivityID:    activityID,
+				Name:          r.Name,
+				Description:   r.Description,
+				Price:         r.Price,
+				Quota:         r.Quota,
+				SaleStartTime: r.SaleStartTime,
+				SaleEndTime:   r.SaleEndTime,
+				Sort:          r.Sort,
+				Status:        r.Status,
+			}
+
+			if r.ID == 0 {
+				if err := createTicket(tx, &ticket); err != nil {
+					return err
+				}
+				continue
+			}
+
+			res := tx.Model(&model.ActivityTicket{}).
+				Where("id = ? AND activity_id = ?", r.ID, activityID).
+				Updates(map[string]any{
+					"name":            ticket.Name,
+					"description":     ticket.Description,
+					"price":           ticket.Price,
+					"quota":           ticket.Quota,
+					"sale_start_time": ticket.SaleStartTime,
+					"sale_end_time":   ticket.SaleEndTime,
+					"sort":            ticket.Sort,
+					"status":          ticket.Status,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errcode.ErrTicketNotFound
+			}
+			kept[r.ID] = true
+		}
+
+		// 删除未提交的票种（存在有效报名时拒绝）
+		for _, t := range existing {
+			if kept[t.ID] {
+				continue
+			}
+			var count int64
+			tx.Model(&model.Registration{}).
+				Where("ticket_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", t.ID).
+				Count(&count)
+			if count > 0 {
+				return errcode.ErrTicketHasRegistrations
+			}
+			if err := tx.Delete(&model.ActivityTicket{}, t.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// createTicket 创建票种
+// status 列默认值为 1（在售），结构体插入时零值会被替换为默认值，以 map 插入保证停售票种按原值写入（不回填 ID）
+func createTicket(tx *gorm.DB, ticket *model.ActivityTicket) error {
+	now := time.Now()
+	return tx.Model(&model.ActivityTicket{}).Create(map[string]any{
+		"created_at":      now,
+		"updated_at":      now,
+		"activity_id":     ticket.ActivityID,
+		"name":            ticket.Name,
+		"description":     ticket.Description,
+		"price":           ticket.Price,
+		"quota":           ticket.Quota,
+		"sale_start_time": ticket.SaleStartTime,
+		"sale_end_time":   ticket.SaleEndTime,
+		"sort":            ticket.Sort,
+		"status":          ticket.Status,
+	}).Error
+}
+
+// CheckAvailable 校验票种是否属于活动、在售且有余量
+func (s *ActivityTicketService) CheckAvailable(ctx context.Context, activityID, ticketID int64, quantity int) (*model.ActivityTicket, error) {
+	var ticket model.ActivityTicket
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND activity_id = ?", ticketID, activityID).
+		First(&ticket).Error; err != nil {
+		return nil, errcode.ErrTicketNotFound
+	}
+
+	if ticket.Status != 1 {
+		return nil, errcode.ErrTicketNotOnSale
+	}
+
+	// 校验售卖时间
+	now := time.Now()
+	if ticket.SaleStartTime != nil && now.Before(*ticket.SaleStartTime) {
+		return nil, errcode.ErrTicketNotOnSale
+	}
+	if ticket.SaleEndTime != nil && now.After(*ticket.SaleEndTime) {
+		return nil, errcode.ErrTicketNotOnSale
+	}
+
+	// 校验票种余量
+	if ticket.Quota > 0 {
+		var count int64
+		s.db.WithContext(ctx).Model(&model.Registration{}).
+			Where("ticket_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", ticketID).
+			Count(&count)
+		if count+int64(quantity) > int64(ticket.Quota) {
+			return nil, errcode.ErrTicketSoldOut
+		}
+	}
+
+	return &ticket, nil
+}
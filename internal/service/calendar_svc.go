@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/ical"
+)
+
+const (
+	// calendarProdID 日历产品标识
+	calendarProdID = "-//go-mountain//activities//ZH"
+	// calendarUIDDomain 事件 UID 的域名部分，保证 UID 全局唯一
+	calendarUIDDomain = "go-mountain"
+	// publicFeedLimit 公开日历输出的最近活动数量
+	publicFeedLimit = 200
+)
+
+// CalendarService 日历订阅服务
+type CalendarService struct {
+	db          *gorm.DB
+	activitySvc *ActivityService
+}
+
+// NewCalendarService 创建日历订阅服务
+func NewCalendarService(db *gorm.DB, activitySvc *ActivityService) *CalendarService {
+	return &CalendarService{db: db, activitySvc: activitySvc}
+}
+
+// PublicFeed 公开活动日历
+// 已发布的活动输出为 CONFIRMED 事件；发布后又撤回为草稿或被删除的活动输出为 CANCELLED 事件，
+// 撤回和删除时都会递增 SEQUENCE，订阅方据此从日历中移除
+func (s *CalendarService) PublicFeed(ctx context.Context) (*ical.Calendar, error) {
+	list, _, err := s.activitySvc.ListForMP(ctx, 1, publicFeedLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 发布过但已删除或撤回为草稿的活动
+	var cancelled []model.Activity
+	err = s.db.WithContext(ctx).Unscoped().
+		Where("published_at IS NOT NULL AND (deleted_at IS NOT NULL OR status = 0)").
+		Order("start_time DESC").
+		Limit(publicFeedLimit).
+		Find(&cancelled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &ical.Calendar{Name: "活动日历", ProdID: calendarProdID}
+	for _, a := range list {
+		cal.Events = append(cal.Events, activityEvent(&a.Activity, publicEventUID(a.ID), a.Sequence, ical.StatusConfirmed))
+	}
+	for i := range cancelled {
+		a := &cancelled[i]
+		cal.Events = append(cal.Events, activityEvent(a, publicEventUID(a.ID), a.Sequence, ical.StatusCancelled))
+	}
+	return cal, nil
+}
+
+// publicEventUID 公开日历中活动事件的 UID
+func publicEventUID(activityID int64) string {
+	return fmt.Sprintf("activity-%d@%s", activityID, calendarUIDDomain)
+}
+
+// activityEvent 将活动映射为日历事件
+func activityEvent(a *model.Activity, uid string, sequence int, status string) ical.Event {
+	return ical.Event{
+		UID:         uid,
+		Sequence:    sequence,
+		Start:       a.StartTime,
+		End:         a.EndTime,
+		Summary:     a.Title,
+		Description: a.Description,
+		Location:    a.Location,
+		Status:      status,
+		Updated:     a.UpdatedAt,
+	}
+}
+
+// UserToken 获取小程序用户的个人日历订阅令牌，reset 为 true 时重新生成（旧订阅地址失效）
+func (s *CalendarService) UserToken(ctx context.Context, userID int64, reset bool) (string, error) {
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return "", errcode.ErrNotFound
+	}
+
+	if user.CalendarToken != "" && !reset {
+		return user.CalendarToken, nil
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := s.db.WithContext(ctx).Model(&user).Update("calendar_token", token).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// calendarEntry 个人日历事件的聚合状态（团体报名按订单合并为一个事件）
+type calendarEntry struct {
+	uid      string
+	activity model.Activity
+	rank     int // 0:待定 1:已确认 2:已取消
+	updated  time.Time
+}
+
+// UserFeed 个人报名日历
+// 单人报名按报名记录、团体报名按订单生成事件；报名取消或审核未通过、活动撤回为草稿或被删除后输出 CANCELLED 事件，
+// SEQUENCE 为活动修改次数加上报名状态序号（待定 → 已确认 → 已取消 单调递增）
+func (s *CalendarService) UserFeed(ctx context.Context, token string) (*ical.Calendar, error) {
+	if token == "" {
+		return nil, errcode.ErrNotFound
+	}
+	var user model.User
+	if err := s.db.WithContext(ctx).Where("calendar_token = ?", token).First(&user).Error; err != nil {
+		return nil, errcode.ErrNotFound
+	}
+
+	// 已删除的活动也需加载，以输出取消事件
+	var regs []model.Registration
+	err := s.db.WithContext(ctx).
+		Preload("Activity", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", user.ID).
+		Order("id").
+		Find(&regs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		entries []*calendarEntry
+		byKey   = make(map[string]*calendarEntry)
+	)
+	for _, r := range regs {
+		if r.Activity == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("registration-%d", r.ID)
+		if r.OrderID != nil {
+			key = fmt.Sprintf("order-%d", *r.OrderID)
+		}
+
+		rank := 2
+		switch r.Status {
+		case 0, 4:
+			rank = 0
+		case 1:
+			rank = 1
+		}
+
+		e, ok := byKey[key]
+		if !ok {
+			e = &calendarEntry{
+				uid:      fmt.Sprintf("%s@%s", key, calendarUIDDomain),
+				activity: *r.Activity,
+				rank:     rank,
+			}
+			byKey[key] = e
+			entries = append(entries, e)
+		}
+		// 订单内任一报名人有效即视为有效，取最"有效"的状态
+		if rank < 2 && (e.rank == 2 || rank > e.rank) {
+			e.rank = rank
+		}
+		if r.UpdatedAt.After(e.updated) {
+			e.updated = r.UpdatedAt
+		}
+	}
+
+	statuses := []string{ical.StatusTentative, ical.StatusConfirmed, ical.StatusCancelled}
+	cal := &ical.Calendar{Name: "我的报名", ProdID: calendarProdID}
+	for _, e := range entries {
+		// 活动撤回为草稿或被删除时报名随之失效
+		if e.activity.Status == 0 || e.activity.DeletedAt.Valid {
+			e.rank = 2
+		}
+		ev := activityEvent(&e.activity, e.uid, e.activity.Sequence+e.rank, statuses[e.rank])
+		if e.updated.After(ev.Updated) {
+			ev.Updated = e.updated
+		}
+		cal.Events = append(cal.Events, ev)
+	}
+	return cal, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+)
+
+// checkinTokenVersion 签到码格式版本，变更签名算法时递增
+const checkinTokenVersion = "c1"
+
+// CheckinService 活动签到服务
+// 签到码为 HMAC-SHA256 签名的报名标识，生成与校验均在本地完成
+type CheckinService struct {
+	db  *gorm.DB
+	key []byte
+}
+
+// NewCheckinService 创建签到服务，签名密钥由 JWT 密钥派生
+func NewCheckinService(db *gorm.DB, secret string) *CheckinService {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("registration-checkin"))
+	return &CheckinService{db: db, key: mac.Sum(nil)}
+}
+
+// sign 计算签到码签名（截断为 16 字节以缩短二维码内容）
+func (s *CheckinService) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Token 生成报名的签到码，格式: c1.{报名ID}.{活动ID}.{签名}
+func (s *CheckinService) Token(reg *model.Registration) string {
+	payload := fmt.Sprintf("%s.%d.%d", checkinTokenVersion, reg.ID, reg.ActivityID)
+	return payload + "." + s.sign(payload)
+}
+
+// parseToken 校验签名并解析出报名 ID 和活动 ID
+func (s *CheckinService) parseToken(token string) (int64, int64, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 4 || parts[0] != checkinTokenVersion {
+		return 0, 0, errcode.ErrCheckinTokenInvalid
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.sign(payload))) {
+		return 0, 0, errcode.ErrCheckinTokenInvalid
+	}
+
+	regID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, errcode.ErrCheckinTokenInvalid
+	}
+	activityID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, errcode.ErrCheckinTokenInvalid
+	}
+	return regID, activityID, nil
+}
+
+// GetToken 获取本人已确认报名的签到码（小程序端渲染为二维码）
+func (s *CheckinService) GetToken(ctx context.Context, userID, regID int64) (string, error) {
+	var reg model.Registration
+	if err := s.db.WithContext(ctx).First(&reg, regID).Error; err != nil {
+		return "", errcode.ErrRegistrationNotFound
+	}
+
+	if reg.UserID != userID {
+		return "", errcode.ErrForbidden
+	}
+
+	if reg.Status != 1 {
+		return "", errcode.ErrCheckinNotAllowed
+	}
+
+	return s.Token(&reg), nil
+}
+
+// Scan 扫码签到：校验签名、活动归属和报名状态，记录签到时间与操作人
+func (s *CheckinService) Scan(ctx context.Context, activityID int64, token string, operatorID int64) (*model.Registration, error) {
+	regID, tokenActivityID, err := s.parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenActivityID != activityID {
+		return nil, errcode.ErrCheckinWrongActivity
+	}
+
+	var reg model.Registration
+	if err := s.db.WithContext(ctx).First(&reg, regID).Error; err != nil {
+		return nil, errcode.ErrRegistrationNotFound
+	}
+
+	if reg.ActivityID != activityID {
+		return nil, errcode.ErrCheckinWrongActivity
+	}
+
+	if reg.Status != 1 {
+		return nil, errcode.ErrCheckinNotAllowed
+	}
+
+	if reg.CheckedInAt != nil {
+		return nil, errcode.ErrAlreadyCheckedIn
+	}
+
+	// 条件更新防止并发重复签到
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("id = ? AND checked_in_at IS NULL", reg.ID).
+		Updates(map[string]any{
+			"checked_in_at": &now,
+			"checked_in_by": operatorID,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errcode.ErrAlreadyCheckedIn
+	}
+
+	reg.CheckedInAt = &now
+	reg.CheckedInBy = &operatorID
+	return &reg, nil
+}
+
+// AttendanceItem 签到报表明细
+type AttendanceItem struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Phone        string     `json:"phone"`
+	TicketName   string     `json:"ticket_name"`
+	CheckedInAt  *time.Time `json:"checked_in_at"`
+	OperatorName string     `json:"operator_name"`
+}
+
+// AttendanceReport 活动签到报表
+type AttendanceReport struct {
+	ActivityID   int64            `json:"activity_id"`
+	Confirmed    int64            `json:"confirmed"`
+	CheckedIn    int64            `json:"checked_in"`
+	NotCheckedIn int64            `json:"not_checked_in"`
+	Items        []AttendanceItem `json:"items"`
+}
+
+// Report 获取活动签到报表（已确认报名的签到情况）
+func (s *CheckinService) Report(ctx context.Context, activityID int64) (*AttendanceReport, error) {
+	var items []AttendanceItem
+	err := s.db.WithContext(ctx).Table("registrations").
+		Select("registrations.id, registrations.name, registrations.phone, registrations.checked_in_at, activity_tickets.name as ticket_name, backend_users.username as operator_name").
+		Joins("LEFT JOIN activity_tickets ON registrations.ticket_id = activity_tickets.id").
+		Joins("LEFT JOIN backend_users ON registrations.checked_in_by = backend_users.id").
+		Where("registrations.activity_id = ? AND registrations.status = 1 AND registrations.deleted_at IS NULL", activityID).
+		Order("registrations.checked_in_at IS NULL, registrations.checked_in_at, registrations.id").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AttendanceReport{
+		ActivityID: activityID,
+		Confirmed:  int64(len(items)),
+		Items:      items,
+	}
+	for _, it := range items {
+		if it.CheckedInAt != nil {
+			report.CheckedIn++
+		}
+	}
+	report.NotCheckedIn = report.Confirmed - report.CheckedIn
+	return report, nil
+}
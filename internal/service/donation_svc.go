@@ -0,0 +1,512 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/mask"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/pdf"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// maxDonationAmount 单笔捐赠金额上限（100 万元）
+const maxDonationAmount money.Money = 1000000 * 100
+
+// DonationService 募捐项目与捐赠服务
+type DonationService struct {
+	repo       *repository.BaseRepo[model.DonationCampaign]
+	db         *gorm.DB
+	paymentSvc *PaymentService
+	configSvc  *SystemConfigService
+}
+
+// NewDonationService 创建募捐服务
+func NewDonationService(db *gorm.DB, paymentSvc *PaymentService, configSvc *SystemConfigService) *DonationService {
+	return &DonationService{
+		repo:       repository.NewBaseRepo[model.DonationCampaign](db),
+		db:         db,
+		paymentSvc: paymentSvc,
+		configSvc:  configSvc,
+	}
+}
+
+// CampaignListItem 募捐项目列表项（含实时募捐进度）
+type CampaignListItem struct {
+	model.DonationCampaign
+	RaisedAmount money.Money `json:"raised_amount"` // 已支付捐赠合计
+	DonorCount   int64       `json:"donor_count"`   // 捐赠人数（按用户去重）
+	Progress     float64     `json:"progress"`      // 完成百分比，保留一位小数，可超过 100
+}
+
+// campaignListSelect 募捐项目查询字段（已筹金额、捐赠人数以子查询统计）
+const campaignListSelect = "donation_campaigns.*, " +
+	"(SELECT COALESCE(SUM(donations.amount), 0) FROM donations WHERE donations.campaign_id = donation_campaigns.id AND donations.status = 1 AND donations.deleted_at IS NULL) as raised_amount, " +
+	"(SELECT COUNT(DISTINCT donations.user_id) FROM donations WHERE donations.campaign_id = donation_campaigns.id AND donations.status = 1 AND donations.deleted_at IS NULL) as donor_count"
+
+// fillProgress 按目标金额计算完成百分比
+func fillProgress(list []CampaignListItem) {
+	for i := range list {
+		if list[i].GoalAmount > 0 {
+			list[i].Progress = math.Round(float64(list[i].RaisedAmount)/float64(list[i].GoalAmount)*1000) / 10
+		}
+	}
+}
+
+// List 获取募捐项目列表（后台管理）
+func (s *DonationService) List(ctx context.Context, page, pageSize int, status int) ([]CampaignListItem, int64, error) {
+	var (
+		list  []CampaignListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("donation_campaigns").
+		Select(campaignListSelect).
+		Where("donation_campaigns.deleted_at IS NULL")
+
+	if status >= 0 {
+		db = db.Where("donation_campaigns.status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("donation_campaigns.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	fillProgress(list)
+	return list, total, err
+}
+
+// Get 获取募捐项目详情
+func (s *DonationService) Get(ctx context.Context, id int64) (*CampaignListItem, error) {
+	return s.get(ctx, "donation_campaigns.id = ?", id)
+}
+
+// get 按条件查询单个募捐项目
+func (s *DonationService) get(ctx context.Context, query string, args ...any) (*CampaignListItem, error) {
+	var item CampaignListItem
+	err := s.db.WithContext(ctx).Table("donation_campaigns").
+		Select(campaignListSelect).
+		Where(query, args...).
+		Where("donation_campaigns.deleted_at IS NULL").
+		First(&item).Error
+	if err != nil {
+		return nil, errcode.ErrCampaignNotFound
+	}
+	list := []CampaignListItem{item}
+	fillProgress(list)
+	return &list[0], nil
+}
+
+// Create 创建募捐项目
+func (s *DonationService) Create(ctx context.Context, campaign *model.DonationCampaign) error {
+	return s.repo.Create(ctx, campaign)
+}
+
+// Update 更新募捐项目
+func (s *DonationService) Update(ctx context.Context, id int64, updates map[string]any) error {
+	return s.repo.Update(ctx, id, updates)
+}
+
+// UpdateStatus 更新募捐项目状态
+func (s *DonationService) UpdateStatus(ctx context.Context, id int64, status int) error {
+	return s.repo.Update(ctx, id, map[string]any{"status": status})
+}
+
+// Delete 删除募捐项目，已有支付成功的捐赠时不允许删除
+func (s *DonationService) Delete(ctx context.Context, id int64) error {
+	var count int64
+	s.db.WithContext(ctx).Model(&model.Donation{}).
+		Where("campaign_id = ? AND status IN (1,3)", id).
+		Count(&count)
+	if count > 0 {
+		return errcode.ErrCampaignHasDonations
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// ListForMP 获取募捐项目列表（小程序端，进行中和已结束）
+func (s *DonationService) ListForMP(ctx context.Context, page, pageSize int) ([]CampaignListItem, int64, error) {
+	var (
+		list  []CampaignListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("donation_campaigns").
+		Select(campaignListSelect).
+		Where("donation_campaigns.status IN (1,2) AND donation_campaigns.deleted_at IS NULL")
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("donation_campaigns.status, donation_campaigns.created_at DESC").
+		Offset(offset).Limit(pageSize).Find(&list).Error
+	fillProgress(list)
+	return list, total, err
+}
+
+// GetForMP 获取募捐项目详情（小程序端）
+func (s *DonationService) GetForMP(ctx context.Context, id int64) (*CampaignListItem, error) {
+	return s.get(ctx, "donation_campaigns.id = ? AND donation_campaigns.status IN (1,2)", id)
+}
+
+// checkCampaignOpen 校验募捐项目处于进行中且在募捐期内
+func checkCampaignOpen(c *model.DonationCampaign, now time.Time) error {
+	if c.Status != 1 {
+		return errcode.ErrCampaignNotOpen
+	}
+	if c.StartTime != nil && now.Before(*c.StartTime) {
+		return errcode.ErrCampaignNotOpen
+	}
+	if c.EndTime != nil && now.After(*c.EndTime) {
+		return errcode.ErrCampaignNotOpen
+	}
+	return nil
+}
+
+// DonateRequest 捐赠请求，金额可为预设金额或任意金额
+type DonateRequest struct {
+	Amount    money.Money `json:"amount" binding:"required,gt=0"`
+	Message   string      `json:"message" binding:"max=200"`
+	Anonymous bool        `json:"anonymous"`
+	TradeType string      `json:"trade_type" binding:"omitempty,oneof=jsapi native h5"` // 下单场景，默认为小程序支付
+	ClientIP  string      `json:"-"`                                                    // 用户终端 IP，由 handler 填写
+}
+
+// DonateResult 捐赠下单结果
+type DonateResult struct {
+	Donation  *model.Donation `json:"donation"`
+	Payment   *model.Payment  `json:"payment"`
+	PayParams any             `json:"pay_params"`
+}
+
+// Donate 发起捐赠：创建待支付捐赠记录并按下单场景调用微信下单（小程序、网站扫码或手机浏览器），支付回调后计入募捐进度
+func (s *DonationService) Donate(ctx context.Context, userID int64, campaignID int64, req *DonateRequest) (*DonateResult, error) {
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return nil, errcode.ErrNotFound
+	}
+
+	var campaign model.DonationCampaign
+	if err := s.db.WithContext(ctx).First(&campaign, campaignID).Error; err != nil {
+		return nil, errcode.ErrCampaignNotFound
+	}
+	if err := checkCampaignOpen(&campaign, time.Now()); err != nil {
+		return nil, err
+	}
+
+	amount := req.Amount
+	if amount <= 0 || amount > maxDonationAmount {
+		return nil, errcode.ErrDonationAmountInvalid
+	}
+
+	donation := &model.Donation{
+		CampaignID: campaign.ID,
+		UserID:     userID,
+		Amount:     amount,
+		Message:    req.Message,
+		Anonymous:  req.Anonymous,
+		Status:     0,
+	}
+	if err := s.db.WithContext(ctx).Create(donation).Error; err != nil {
+		return nil, err
+	}
+
+	pay, payParams, err := s.paymentSvc.CreatePrepayOrder(ctx, &PrepayOrder{
+		UserID:      userID,
+		Amount:      amount,
+		BizType:     "donation",
+		BizID:       donation.ID,
+		Description: campaign.Title,
+		TradeType:   req.TradeType,
+		OpenID:      user.OpenID,
+		ClientIP:    req.ClientIP,
+	})
+	if err != nil {
+		// 下单失败时移除未关联支付的捐赠记录
+		s.db.WithContext(ctx).Delete(donation)
+		return nil, err
+	}
+
+	return &DonateResult{Donation: donation, Payment: pay, PayParams: payParams}, nil
+}
+
+// DonationListItem 捐赠记录列表项
+type DonationListItem struct {
+	model.Donation
+	CampaignTitle string `json:"campaign_title"`
+	UserName      string `json:"user_name,omitempty"`
+	OrderNo       string `json:"order_no,omitempty"`
+	ReceiptNo     string `json:"receipt_no,omitempty"`     // 收据编号
+	ReceiptStatus *int   `json:"receipt_status,omitempty"` // 0:已作废 1:有效
+}
+
+// donationQuery 捐赠记录基础查询（关联募捐项目、用户、支付单和收据）
+func (s *DonationService) donationQuery(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table("donations").
+		Select("donations.*, donation_campaigns.title as campaign_title, users.name as user_name, payments.order_no, " +
+			"donation_receipts.serial_no as receipt_no, donation_receipts.status as receipt_status").
+		Joins("LEFT JOIN donation_campaigns ON donations.campaign_id = donation_campaigns.id").
+		Joins("LEFT JOIN users ON donations.user_id = users.id").
+		Joins("LEFT JOIN payments ON donations.payment_id = payments.id").
+		Joins("LEFT JOIN donation_receipts ON donation_receipts.donation_id = donations.id AND donation_receipts.deleted_at IS NULL").
+		Where("donations.deleted_at IS NULL")
+}
+
+// ListDonations 获取捐赠记录（后台管理），status 为 -1 时不过滤
+func (s *DonationService) ListDonations(ctx context.Context, page, pageSize int, campaignID int64, status int) ([]DonationListItem, int64, error) {
+	var (
+		list  []DonationListItem
+		total int64
+	)
+
+	db := s.donationQuery(ctx)
+	if campaignID > 0 {
+		db = db.Where("donations.campaign_id = ?", campaignID)
+	}
+	if status >= 0 {
+		db = db.Where("donations.status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("donations.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// GetByUser 获取用户的捐赠记录（小程序端，不含未支付记录）
+func (s *DonationService) GetByUser(ctx context.Context, userID int64, page, pageSize int) ([]DonationListItem, int64, error) {
+	var (
+		list  []DonationListItem
+		total int64
+	)
+
+	db := s.donationQuery(ctx).Where("donations.user_id = ? AND donations.status <> 0", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("donations.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// DonorWallItem 捐赠墙条目，匿名捐赠不展示姓名，其余姓名脱敏
+type DonorWallItem struct {
+	ID      int64       `json:"id"`
+	Name    string      `json:"name"`
+	Avatar  string      `json:"avatar"`
+	Amount  money.Money `json:"amount"`
+	Message string      `json:"message"`
+	PaidAt  *time.Time  `json:"paid_at"`
+}
+
+// anonymousDonorName 匿名捐赠在捐赠墙上展示的名称
+const anonymousDonorName = "爱心人士"
+
+// Donors 获取募捐项目的捐赠墙（公开），按支付时间倒序
+func (s *DonationService) Donors(ctx context.Context, campaignID int64, page, pageSize int) ([]DonorWallItem, int64, error) {
+	var (
+		rows []struct {
+			DonorWallItem
+			Anonymous bool
+		}
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("donations").
+		Select("donations.id, users.name, users.avatar, donations.amount, donations.message, donations.paid_at, donations.anonymous").
+		Joins("LEFT JOIN users ON donations.user_id = users.id").
+		Where("donations.campaign_id = ? AND donations.status = 1 AND donations.deleted_at IS NULL", campaignID)
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("donations.paid_at DESC, donations.id DESC").Offset(offset).Limit(pageSize).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]DonorWallItem, 0, len(rows))
+	for _, r := range rows {
+		item := r.DonorWallItem
+		if r.Anonymous || item.Name == "" {
+			item.Name, item.Avatar = anonymousDonorName, ""
+		} else {
+			item.Name = mask.Name(item.Name)
+		}
+		list = append(list, item)
+	}
+	return list, total, nil
+}
+
+// receiptSerialNo 收据编号: JZ + 支付日期 + 8 位捐赠 ID，随捐赠 ID 唯一且递增
+func receiptSerialNo(donationID int64, paidAt time.Time) string {
+	return fmt.Sprintf("JZ%s%08d", paidAt.Format("20060102"), donationID)
+}
+
+// issueDonationReceipt 为支付成功的捐赠开具收据（在支付回调事务中调用，重复调用不会重复开具）
+func issueDonationReceipt(tx *gorm.DB, donationID int64, paidAt time.Time) error {
+	var donation model.Donation
+	if err := tx.First(&donation, donationID).Error; err != nil {
+		return err
+	}
+
+	donorName := anonymousDonorName
+	var user model.User
+	if err := tx.First(&user, donation.UserID).Error; err == nil && user.Name != "" {
+		donorName = user.Name
+	}
+
+	receipt := &model.DonationReceipt{
+		SerialNo:   receiptSerialNo(donation.ID, paidAt),
+		DonationID: donation.ID,
+		CampaignID: donation.CampaignID,
+		UserID:     donation.UserID,
+		DonorName:  donorName,
+		Amount:     donation.Amount,
+		IssuedAt:   paidAt,
+		Status:     1,
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(receipt).Error
+}
+
+// voidDonationReceipt 作废捐赠对应的有效收据（在退款事务中调用）
+func voidDonationReceipt(tx *gorm.DB, donationID int64, reason string) error {
+	now := time.Now()
+	return tx.Model(&model.DonationReceipt{}).
+		Where("donation_id = ? AND status = 1", donationID).
+		Updates(map[string]any{
+			"status":      0,
+			"voided_at":   &now,
+			"void_reason": reason,
+		}).Error
+}
+
+// ReceiptListItem 收据列表项
+type ReceiptListItem struct {
+	model.DonationReceipt
+	CampaignTitle string `json:"campaign_title"`
+}
+
+// ListReceipts 获取收据列表（后台管理），status 为 -1 时不过滤
+func (s *DonationService) ListReceipts(ctx context.Context, page, pageSize int, campaignID int64, status int) ([]ReceiptListItem, int64, error) {
+	var (
+		list  []ReceiptListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("donation_receipts").
+		Select("donation_receipts.*, donation_campaigns.title as campaign_title").
+		Joins("LEFT JOIN donation_campaigns ON donation_receipts.campaign_id = donation_campaigns.id").
+		Where("donation_receipts.deleted_at IS NULL")
+	if campaignID > 0 {
+		db = db.Where("donation_receipts.campaign_id = ?", campaignID)
+	}
+	if status >= 0 {
+		db = db.Where("donation_receipts.status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("donation_receipts.id DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// GetReceipt 获取收据
+func (s *DonationService) GetReceipt(ctx context.Context, id int64) (*model.DonationReceipt, error) {
+	var receipt model.DonationReceipt
+	if err := s.db.WithContext(ctx).First(&receipt, id).Error; err != nil {
+		return nil, errcode.ErrReceiptNotFound
+	}
+	return &receipt, nil
+}
+
+// GetReceiptForUser 获取本人捐赠的收据（小程序端）
+func (s *DonationService) GetReceiptForUser(ctx context.Context, userID, donationID int64) (*model.DonationReceipt, error) {
+	var receipt model.DonationReceipt
+	if err := s.db.WithContext(ctx).Where("donation_id = ?", donationID).First(&receipt).Error; err != nil {
+		return nil, errcode.ErrReceiptNotFound
+	}
+	if receipt.UserID != userID {
+		return nil, errcode.ErrForbidden
+	}
+	return &receipt, nil
+}
+
+// VoidReceipt 作废收据（后台，如线下退款或部分退款后）
+func (s *DonationService) VoidReceipt(ctx context.Context, id int64, reason string) error {
+	receipt, err := s.GetReceipt(ctx, id)
+	if err != nil {
+		return err
+	}
+	if receipt.Status == 0 {
+		return errcode.ErrReceiptVoided
+	}
+	return voidDonationReceipt(s.db.WithContext(ctx), receipt.DonationID, reason)
+}
+
+// WriteReceiptPDF 输出捐赠收据 PDF，已作废的收据加盖作废标记
+func (s *DonationService) WriteReceiptPDF(ctx context.Context, w io.Writer, receipt *model.DonationReceipt) error {
+	var campaign model.DonationCampaign
+	s.db.WithContext(ctx).Unscoped().First(&campaign, receipt.CampaignID)
+	issuer := s.configSvc.GetString(ctx, "site.name")
+
+	// A5 横版
+	doc := pdf.New(pdf.A4Width, pdf.A4Height/2)
+	width, height := doc.Width(), doc.Height()
+	p := doc.AddPage()
+
+	p.SetStrokeColor(0.6, 0.1, 0.1)
+	p.Rect(20, 20, width-40, height-40, 1.5)
+
+	p.SetFillColor(0.6, 0.1, 0.1)
+	p.TextCenter(height-70, 26, "捐赠收据")
+
+	p.SetFillColor(0.3, 0.3, 0.3)
+	serial := "编号：" + receipt.SerialNo
+	p.Text(width-50-pdf.TextWidth(serial, 10), height-95, 10, serial)
+
+	lines := []string{
+		"捐赠人：" + receipt.DonorName,
+		"捐赠项目：" + campaign.Title,
+		"捐赠金额（小写）：人民币 " + receipt.Amount.String() + " 元",
+		"捐赠金额（大写）：" + receipt.Amount.Upper(),
+		"捐赠日期：" + receipt.IssuedAt.Format("2006年01月02日"),
+	}
+	p.SetFillColor(0.1, 0.1, 0.1)
+	for i, line := range lines {
+		p.Text(60, height-135-float64(i)*28, 13, line)
+	}
+	p.Text(60, 70, 12, "感谢您的爱心捐赠！")
+
+	p.Text(width-60-pdf.TextWidth(issuer, 13), 70, 13, issuer)
+
+	if receipt.Status == 0 {
+		p.SetStrokeColor(0.85, 0.1, 0.1)
+		p.SetFillColor(0.85, 0.1, 0.1)
+		p.Rect(width-190, height-210, 120, 50, 2)
+		p.Text(width-175, height-196, 28, "已作废")
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// OperationLogService 操作日志服务
+type OperationLogService struct {
+	repo *repository.BaseRepo[model.OperationLog]
+	db   *gorm.DB
+}
+
+// NewOperationLogService 创建操作日志服务
+func NewOperationLogService(db *gorm.DB) *OperationLogService {
+	return &OperationLogService{
+		repo: repository.NewBaseRepo[model.OperationLog](db),
+		db:   db,
+	}
+}
+
+// Record 写入一条操作日志，detail 序列化为 JSON 保存
+func (s *OperationLogService) Record(ctx context.Context, log *model.OperationLog, detail any) error {
+	if detail != nil {
+		b, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		log.Detail = b
+	}
+	return s.repo.Create(ctx, log)
+}
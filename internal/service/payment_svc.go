@@ -2,20 +2,19 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"math"
-	"os"
+	"hash/fnv"
+	"net/http"
+	"sync"
 	"time"
 
-	"github.com/ArtisanCloud/PowerLibs/v3/object"
-	"github.com/ArtisanCloud/PowerWeChat/v3/src/payment"
-	orderRequest "github.com/ArtisanCloud/PowerWeChat/v3/src/payment/order/request"
-	refundRequest "github.com/ArtisanCloud/PowerWeChat/v3/src/payment/refund/request"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/zzhtl/go-mountain/internal/model"
 	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/payprovider"
 	"github.com/zzhtl/go-mountain/internal/repository"
 )
 
@@ -24,6 +23,13 @@ type PaymentService struct {
 	repo            *repository.BaseRepo[model.Payment]
 	db              *gorm.DB
 	systemConfigSvc *SystemConfigService
+	mock            *payprovider.Mock
+
+	wechatMu  sync.Mutex
+	wechat    *payprovider.Wechat      // 缓存的微信支付渠道
+	wechatCfg payprovider.WechatConfig // 创建缓存实例时的配置，配置变更后重建
+
+	bizLocks [64]sync.Mutex // 按业务对象分段加锁，串行化同一业务的下单
 }
 
 // NewPaymentService 创建支付服务
@@ -32,6 +38,7 @@ func NewPaymentService(db *gorm.DB, systemConfigSvc *SystemConfigService) *Payme
 		repo:            repository.NewBaseRepo[model.Payment](db),
 		db:              db,
 		systemConfigSvc: systemConfigSvc,
+		mock:            payprovider.NewMock(),
 	}
 }
 
@@ -40,68 +47,86 @@ func (s *PaymentService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// GetPaymentApp 根据数据库中的系统配置创建 PowerWeChat Payment 实例
-func (s *PaymentService) GetPaymentApp(ctx context.Context) (*payment.Payment, error) {
-	cfg := s.systemConfigSvc.GetWechatPayConfig(ctx)
+// Provider 按系统配置 payment.provider 获取新订单使用的支付渠道（wechat / mock）
+func (s *PaymentService) Provider(ctx context.Context) (payprovider.Provider, error) {
+	if s.systemConfigSvc.GetString(ctx, "payment.provider") == "mock" {
+		return s.mock, nil
+	}
+	return s.Wechat(ctx)
+}
+
+// providerFor 获取渠道名称（见 payprovider.ProviderOf）对应的渠道，已有订单的查单、退款和回调均按下单时的渠道处理；
+// 模拟支付仅在 payment.provider 为 mock 时可用，防止生产环境伪造支付通知
+func (s *PaymentService) providerFor(ctx context.Context, name string) (payprovider.Provider, error) {
+	if name == payprovider.ProviderMock {
+		if s.systemConfigSvc.GetString(ctx, "payment.provider") != "mock" {
+			return nil, errcode.ErrMockPayDisabled
+		}
+		return s.mock, nil
+	}
+	return s.Wechat(ctx)
+}
 
-	appID := cfg["wechat.app_id"]
-	mchID := cfg["wechat.mch_id"]
-	mchApiV3Key := cfg["wechat.mch_api_v3_key"]
-	serialNo := cfg["wechat.mch_serial_no"]
-	privateKey := cfg["wechat.mch_private_key"]
-	notifyURL := cfg["wechat.notify_url"]
+// wechatReleaseDelay 配置变更后旧的微信支付实例延迟释放，等待仍在使用它的请求完成
+const wechatReleaseDelay = time.Minute
 
-	if appID == "" || mchID == "" || mchApiV3Key == "" || privateKey == "" {
-		return nil, fmt.Errorf("微信支付配置不完整，请在后台【系统配置】中完善微信支付相关配置")
+// Wechat 获取微信支付渠道，实例按系统配置缓存复用，wechat.* 配置变更后重建
+func (s *PaymentService) Wechat(ctx context.Context) (*payprovider.Wechat, error) {
+	cfg := s.wechatConfig(ctx)
+
+	s.wechatMu.Lock()
+	defer s.wechatMu.Unlock()
+
+	if s.wechat != nil && s.wechatCfg == cfg {
+		return s.wechat, nil
 	}
 
-	// 将私钥内容写入临时文件，PowerWeChat 需要文件路径
-	keyFile, err := os.CreateTemp("", "wechat_mch_key_*.pem")
+	w, err := payprovider.NewWechat(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("创建临时密钥文件失败: %w", err)
-	}
-	if _, err := keyFile.WriteString(privateKey); err != nil {
-		keyFile.Close()
-		os.Remove(keyFile.Name())
-		return nil, fmt.Errorf("写入密钥文件失败: %w", err)
-	}
-	keyFile.Close()
-	// 延迟清理临时文件（给 PowerWeChat 足够时间读取）
-	go func() {
-		time.Sleep(10 * time.Second)
-		os.Remove(keyFile.Name())
-	}()
-
-	app, err := payment.NewPayment(&payment.UserConfig{
-		AppID:       appID,
-		MchID:       mchID,
-		MchApiV3Key: mchApiV3Key,
-		KeyPath:     keyFile.Name(),
-		SerialNo:    serialNo,
-		NotifyURL:   notifyURL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("初始化微信支付实例失败: %w", err)
+		return nil, err
+	}
+	if old := s.wechat; old != nil {
+		time.AfterFunc(wechatReleaseDelay, func() { old.Release() })
 	}
+	s.wechat, s.wechatCfg = w, cfg
+	return w, nil
+}
 
-	return app, nil
+// wechatConfig 从系统配置读取微信支付配置
+func (s *PaymentService) wechatConfig(ctx context.Context) payprovider.WechatConfig {
+	cfg := s.systemConfigSvc.GetWechatPayConfig(ctx)
+	return payprovider.WechatConfig{
+		AppID:           cfg["wechat.app_id"],
+		MchID:           cfg["wechat.mch_id"],
+		MchAPIv3Key:     cfg["wechat.mch_api_v3_key"],
+		SerialNo:        cfg["wechat.mch_serial_no"],
+		PrivateKey:      cfg["wechat.mch_private_key"],
+		NotifyURL:       cfg["wechat.notify_url"],
+		RefundNotifyURL: cfg["wechat.refund_notify_url"],
+	}
 }
 
 // PaymentListItem 支付列表项
 type PaymentListItem struct {
 	model.Payment
-	UserName string `json:"user_name"`
+	UserName        string      `json:"user_name"`
+	RefundingAmount money.Money `json:"refunding_amount"` // 退款中金额
+	AbnormalRefunds int64       `json:"abnormal_refunds"` // 退款异常笔数，需人工处理
 }
 
-// List 获取支付记录列表（后台管理）
-func (s *PaymentService) List(ctx context.Context, page, pageSize int, status int, bizType string) ([]PaymentListItem, int64, error) {
+// paymentListSelect 支付列表查询字段（退款中金额、退款异常笔数以子查询统计）
+const paymentListSelect = "payments.*, " +
+	"(SELECT COALESCE(SUM(refunds.amount), 0) FROM refunds WHERE refunds.payment_id = payments.id AND refunds.status = 0 AND refunds.deleted_at IS NULL) as refunding_amount, " +
+	"(SELECT COUNT(*) FROM refunds WHERE refunds.payment_id = payments.id AND refunds.status = 3 AND refunds.deleted_at IS NULL) as abnormal_refunds"
+
+// List 获取支付记录列表（后台管理），abnormal 为 true 时仅返回存在退款异常的支付
+func (s *PaymentService) List(ctx context.Context, page, pageSize int, status int, bizType string, abnormal bool) ([]PaymentListItem, int64, error) {
 	var (
 		list  []PaymentListItem
 		total int64
 	)
 
 	db := s.db.WithContext(ctx).Table("payments").
-		Select("payments.*").
 		Where("payments.deleted_at IS NULL")
 
 	if status >= 0 {
@@ -110,19 +135,29 @@ func (s *PaymentService) List(ctx context.Context, page, pageSize int, status in
 	if bizType != "" {
 		db = db.Where("payments.biz_type = ?", bizType)
 	}
+	if abnormal {
+		db = db.Where("EXISTS (SELECT 1 FROM refunds WHERE refunds.payment_id = payments.id AND refunds.status = 3 AND refunds.deleted_at IS NULL)")
+	}
 
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * pageSize
-	err := db.Order("payments.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	err := db.Select(paymentListSelect).Order("payments.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
 	return list, total, err
 }
 
-// Get 获取支付详情
+// Get 获取支付详情（含退款流水）
 func (s *PaymentService) Get(ctx context.Context, id int64) (*model.Payment, error) {
-	return s.repo.GetByID(ctx, id)
+	var pay model.Payment
+	err := s.db.WithContext(ctx).
+		Preload("Refunds", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		First(&pay, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pay, nil
 }
 
 // GenerateOrderNo 生成订单号
@@ -135,79 +170,229 @@ func (s *PaymentService) GenerateRefundNo() string {
 	return fmt.Sprintf("R%s%06d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000000)
 }
 
-// CreatePrepayOrder 创建预支付订单，调用微信 JSAPI 下单接口
-// 返回前端小程序拉起支付所需的参数
-func (s *PaymentService) CreatePrepayOrder(ctx context.Context, userID int64, amount float64, bizType string, bizID int64, openID string, description string) (*model.Payment, *object.StringMap, error) {
-	orderNo := s.GenerateOrderNo()
+// prepayExpire 预支付订单有效期，超时未支付的订单在对账时关闭
+const prepayExpire = 30 * time.Minute
+
+// prepayReuseWindow 待支付订单可被重复下单复用的时间，预留 5 分钟供用户完成支付
+const prepayReuseWindow = prepayExpire - 5*time.Minute
+
+// h5ReuseWindow H5 支付跳转链接有效期仅 5 分钟，预留 1 分钟供用户跳转
+const h5ReuseWindow = 4 * time.Minute
+
+// PrepayOrder 预支付下单参数
+type PrepayOrder struct {
+	UserID      int64
+	Amount      money.Money
+	BizType     string
+	BizID       int64
+	Description string
+	TradeType   string // 下单场景 jsapi（小程序）/native（网站扫码）/h5（手机浏览器），为空时为 jsapi
+	OpenID      string // JSAPI 下单必填
+	ClientIP    string // H5 下单必填
+}
 
-	// 获取 PowerWeChat 支付实例
-	app, err := s.GetPaymentApp(ctx)
+// CreatePrepayOrder 创建预支付订单，按系统配置的支付渠道和下单场景下单，支付方式记录在 Payment.PayType
+// 返回前端拉起支付所需的参数。下单按业务对象幂等：同一业务存在金额、支付方式一致且未临近过期的待支付订单时
+// 直接复用其预支付单，其余待支付订单先在渠道侧关单再创建新订单（渠道侧已支付的按支付成功处理）
+func (s *PaymentService) CreatePrepayOrder(ctx context.Context, req *PrepayOrder) (*model.Payment, any, error) {
+	unlock := s.lockBiz(req.BizType, req.BizID)
+	defer unlock()
+
+	provider, err := s.Provider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	payType, err := provider.PayType(req.TradeType)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// 金额转为分（微信支付以分为单位）
-	totalCents := int(math.Round(amount * 100))
-
-	// 调用微信 JSAPI 统一下单
-	result, err := app.Order.JSAPITransaction(ctx, &orderRequest.RequestJSAPIPrepay{
-		Description: description,
-		OutTradeNo:  orderNo,
-		TimeExpire:  time.Now().Add(30 * time.Minute).Format(time.RFC3339),
-		Amount: &orderRequest.JSAPIAmount{
-			Total:    totalCents,
-			Currency: "CNY",
-		},
-		Payer: &orderRequest.JSAPIPayer{
-			OpenID: openID,
-		},
-	})
+	var pending []model.Payment
+	err = s.db.WithContext(ctx).
+		Where("biz_type = ? AND biz_id = ? AND status = 0 AND pay_type <> ?", req.BizType, req.BizID, "offline").
+		Order("created_at DESC").
+		Find(&pending).Error
 	if err != nil {
-		return nil, nil, fmt.Errorf("微信下单失败: %w", err)
+		return nil, nil, err
 	}
 
-	if result.PrepayID == "" {
-		return nil, nil, fmt.Errorf("微信下单失败: 未获取到 prepay_id")
+	reuseWindow := prepayReuseWindow
+	if payType == payprovider.PayTypeWechatH5 {
+		reuseWindow = h5ReuseWindow
 	}
 
-	prepayID := result.PrepayID
+	// 复用最近一笔可用的待支付订单，其余的关闭
+	var (
+		reused    *model.Payment
+		payParams any
+	)
+	if len(pending) > 0 {
+		pay := &pending[0]
+		if pay.UserID == req.UserID && pay.Amount == req.Amount && pay.PayType == payType &&
+			pay.PrepayID != "" && time.Since(pay.CreatedAt) < reuseWindow {
+			if payParams, err = provider.PayParams(pay.PayType, pay.OrderNo, pay.PrepayID); err == nil {
+				reused, pending = pay, pending[1:]
+			}
+		}
+	}
+	for i := range pending {
+		if err := s.closePending(ctx, &pending[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if reused != nil {
+		return reused, payParams, nil
+	}
+
+	orderNo := s.GenerateOrderNo()
+	result, err := provider.Prepay(ctx, &payprovider.PrepayRequest{
+		OrderNo:     orderNo,
+		TradeType:   req.TradeType,
+		Description: req.Description,
+		Amount:      req.Amount,
+		OpenID:      req.OpenID,
+		ClientIP:    req.ClientIP,
+		ExpireAt:    time.Now().Add(prepayExpire),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// 创建支付记录
 	pay := &model.Payment{
 		OrderNo:  orderNo,
-		UserID:   userID,
-		Amount:   amount,
-		PayType:  "wechat_jsapi",
+		UserID:   req.UserID,
+		Amount:   req.Amount,
+		PayType:  result.PayType,
 		Status:   0,
-		BizType:  bizType,
-		BizID:    bizID,
-		PrepayID: prepayID,
+		BizType:  req.BizType,
+		BizID:    req.BizID,
+		PrepayID: result.PrepayID,
 	}
 	if err := s.repo.Create(ctx, pay); err != nil {
 		return nil, nil, err
 	}
 
-	// 生成前端小程序拉起支付所需的参数
-	payParams, err := app.JSSDK.BridgeConfig(prepayID, false)
+	return pay, result.PayParams, nil
+}
+
+// lockBiz 锁定业务对象的下单，返回解锁函数
+func (s *PaymentService) lockBiz(bizType string, bizID int64) func() {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", bizType, bizID)
+	mu := &s.bizLocks[h.Sum32()%uint32(len(s.bizLocks))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// closePending 关闭不再使用的待支付订单，先查询渠道订单避免关闭实际已支付的订单：
+// 已支付的按支付成功处理并返回订单已支付，用户支付中的返回支付中，其余在渠道侧关单后标记为支付失败
+func (s *PaymentService) closePending(ctx context.Context, pay *model.Payment) error {
+	provider, err := s.providerFor(ctx, payprovider.ProviderOf(pay.PayType))
+	if err != nil {
+		// 模拟支付停用后遗留的模拟订单没有真实资金往来，直接作废
+		if pay.PayType == payprovider.PayTypeMock {
+			return s.markPayFailed(ctx, pay.ID)
+		}
+		return err
+	}
+
+	order, err := provider.Query(ctx, pay.OrderNo)
 	if err != nil {
-		return nil, nil, fmt.Errorf("生成支付参数失败: %w", err)
+		return err
 	}
+	switch order.TradeState {
+	case payprovider.TradeSuccess:
+		if err := s.applyOrder(ctx, pay, order); err != nil {
+			return err
+		}
+		return errcode.ErrPaymentAlreadyPaid
+	case payprovider.TradeUserPaying:
+		return errcode.ErrPaymentPaying
+	case payprovider.TradeNotPay:
+		if err := provider.Close(ctx, pay.OrderNo); err != nil {
+			return err
+		}
+	}
+	return s.markPayFailed(ctx, pay.ID)
+}
 
-	return pay, payParams.(*object.StringMap), nil
+// closePendingBiz 关闭业务对象的全部待支付在线订单（业务取消时调用），防止用户继续支付已取消的业务
+// 渠道侧已支付的按支付成功处理并返回订单已支付
+func (s *PaymentService) closePendingBiz(ctx context.Context, bizType string, bizID int64) error {
+	unlock := s.lockBiz(bizType, bizID)
+	defer unlock()
+
+	var pending []model.Payment
+	err := s.db.WithContext(ctx).
+		Where("biz_type = ? AND biz_id = ? AND status = 0 AND pay_type <> ?", bizType, bizID, "offline").
+		Find(&pending).Error
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		if err := s.closePending(ctx, &pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// HandleNotify 处理微信支付回调（由 handler 在验签解密后调用）
+// HandlePaidNotify 验签并处理支付渠道的支付结果通知，name 为回调地址对应的渠道名称
+// 同一渠道的各下单场景（如微信 JSAPI、Native、H5）共用回调地址
+func (s *PaymentService) HandlePaidNotify(ctx context.Context, name string, r *http.Request) error {
+	provider, err := s.providerFor(ctx, name)
+	if err != nil {
+		return err
+	}
+	order, err := provider.ParsePaidNotify(r)
+	if err != nil {
+		return err
+	}
+
+	pay, err := s.GetByOrderNo(ctx, order.OrderNo)
+	if err != nil {
+		return err
+	}
+	// 通知须来自下单时的渠道
+	if payprovider.ProviderOf(pay.PayType) != name {
+		return errcode.ErrPaymentNotFound
+	}
+	return s.applyOrder(ctx, pay, order)
+}
+
+// applyOrder 按渠道订单状态更新待支付订单：已支付的按支付回调处理，已关闭或支付失败的标记为支付失败
+func (s *PaymentService) applyOrder(ctx context.Context, pay *model.Payment, order *payprovider.Order) error {
+	switch order.TradeState {
+	case payprovider.TradeSuccess:
+		return s.HandleNotify(ctx, pay.OrderNo, order.TransactionID, order.Raw)
+	case payprovider.TradeClosed, payprovider.TradeRevoked, payprovider.TradePayError:
+		return s.markPayFailed(ctx, pay.ID)
+	}
+	return nil
+}
+
+// orphanRefundReason 报名（订单）已取消或已通过其他支付确认时，对多收款项自动退款的原因
+const orphanRefundReason = "报名已取消或已支付，自动退还重复付款"
+
+// HandleNotify 处理支付成功（支付回调验签解密后或主动查单时调用）
+// 报名或报名订单已取消或已通过其他支付（如线下收款）确认时，该笔支付不再关联业务，提交后自动全额退款
 func (s *PaymentService) HandleNotify(ctx context.Context, orderNo string, transactionID string, notifyData []byte) error {
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	var (
+		pay    model.Payment
+		orphan bool
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 查询支付记录
-		var pay model.Payment
 		if err := tx.Where("order_no = ?", orderNo).First(&pay).Error; err != nil {
 			return errcode.ErrPaymentNotFound
 		}
 
-		// 幂等处理
+		// 幂等处理：重复通知时补做上次未成功的自动退款
 		if pay.Status == 1 {
-			return nil
+			var err error
+			orphan, err = orphanedPayment(tx, &pay)
+			return err
 		}
 
 		now := time.Now()
@@ -222,78 +407,429 @@ func (s *PaymentService) HandleNotify(ctx context.Context, orderNo string, trans
 		}
 
 		// 更新关联业务状态
-		if pay.BizType == "registration" {
+		switch pay.BizType {
+		case "registration":
+			// 条件更新，已取消或已确认的报名不再被确认
+			res := tx.Model(&model.Registration{}).
+				Where("id = ? AND status = 0", pay.BizID).
+				Updates(map[string]any{
+					"status":     1,
+					"payment_id": pay.ID,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			orphan = res.RowsAffected == 0
+		case "registration_order":
+			// 条件更新，已取消或已确认的订单不再被确认
+			res := tx.Model(&model.RegistrationOrder{}).
+				Where("id = ? AND status = 0", pay.BizID).
+				Updates(map[string]any{
+					"status":     1,
+					"payment_id": pay.ID,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if orphan = res.RowsAffected == 0; orphan {
+				return nil
+			}
 			if err := tx.Model(&model.Registration{}).
+				Where("order_id = ? AND status = 0", pay.BizID).
+				Updates(map[string]any{
+					"status":     1,
+					"payment_id": pay.ID,
+				}).Error; err != nil {
+				return err
+			}
+		case "donation":
+			if err := tx.Model(&model.Donation{}).
 				Where("id = ?", pay.BizID).
 				Updates(map[string]any{
 					"status":     1,
 					"payment_id": pay.ID,
+					"paid_at":    &now,
 				}).Error; err != nil {
 				return err
 			}
+			if err := issueDonationReceipt(tx, pay.BizID, now); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
+	if err != nil || !orphan {
+		return err
+	}
+
+	// 退款失败时返回错误，支付回调按失败应答，渠道重发通知时重试退款
+	_, err = s.Refund(ctx, pay.ID, pay.Amount, orphanRefundReason, 0)
+	return err
+}
+
+// orphanedPayment 判断已支付的报名（订单）支付是否未关联到报名或订单（已取消或已通过其他支付确认）且尚未发起退款
+func orphanedPayment(tx *gorm.DB, pay *model.Payment) (bool, error) {
+	var linked int64
+	db := tx.Where("id = ? AND payment_id = ?", pay.BizID, pay.ID)
+	switch pay.BizType {
+	case "registration":
+		db = db.Model(&model.Registration{})
+	case "registration_order":
+		db = db.Model(&model.RegistrationOrder{})
+	default:
+		return false, nil
+	}
+	if err := db.Count(&linked).Error; err != nil {
+		return false, err
+	}
+	if linked > 0 {
+		return false, nil
+	}
+	locked, err := lockedRefundAmount(tx, pay.ID)
+	return locked == 0, err
+}
+
+// RefundRequest 后台退款请求，金额为 0 时退还全部剩余可退金额
+type RefundRequest struct {
+	Amount money.Money `json:"amount" binding:"min=0"`
+	Reason string      `json:"reason" binding:"required,max=80"` // 微信退款原因限 80 字
 }
 
-// RefundOrder 退款，调用微信退款接口
-func (s *PaymentService) RefundOrder(ctx context.Context, paymentID int64) error {
+// RefundOrder 后台退款，可多次部分退款
+func (s *PaymentService) RefundOrder(ctx context.Context, paymentID, operatorID int64, req *RefundRequest) (*model.Refund, error) {
+	amount := req.Amount
+	if amount == 0 {
+		locked, err := lockedRefundAmount(s.db.WithContext(ctx), paymentID)
+		if err != nil {
+			return nil, err
+		}
+		var pay model.Payment
+		if err := s.db.WithContext(ctx).First(&pay, paymentID).Error; err != nil {
+			return nil, errcode.ErrPaymentNotFound
+		}
+		amount = pay.Amount - locked
+	}
+	return s.Refund(ctx, paymentID, amount, req.Reason, operatorID)
+}
+
+// Refund 退款，登记退款流水后调用下单渠道的退款接口，支持多次部分退款
+// operatorID 为发起退款的后台用户，用户自助取消等场景传 0；
+// 在线退款为异步处理，受理后流水为退款中，到账结果由退款通知更新（见 HandleRefundNotify）；
+// 退款成功金额累计达到支付金额时，支付记录及关联业务标记为已退款，部分退款时关联业务状态由调用方负责更新；
+// 捐赠只支持全额退款
+func (s *PaymentService) Refund(ctx context.Context, paymentID int64, amount money.Money, reason string, operatorID int64) (*model.Refund, error) {
+	return s.refund(ctx, paymentID, amount, reason, operatorID, nil)
+}
+
+// refund 退款，link 在登记退款流水的同一事务中执行，用于将流水关联到发起退款的报名，
+// 保证退款结果通知到达时已能找到关联的报名
+func (s *PaymentService) refund(ctx context.Context, paymentID int64, amount money.Money, reason string, operatorID int64, link func(tx *gorm.DB, refund *model.Refund) error) (*model.Refund, error) {
 	var pay model.Payment
 	if err := s.db.WithContext(ctx).First(&pay, paymentID).Error; err != nil {
-		return errcode.ErrPaymentNotFound
+		return nil, errcode.ErrPaymentNotFound
 	}
 
-	if pay.Status != 1 {
-		return errcode.ErrRefundFailed
+	// 线下收款由经办人线下退还，仅记录退款；在线支付调用下单渠道的退款接口
+	var provider payprovider.Provider
+	if pay.PayType != "offline" {
+		var err error
+		if provider, err = s.providerFor(ctx, payprovider.ProviderOf(pay.PayType)); err != nil {
+			return nil, err
+		}
 	}
 
-	// 获取 PowerWeChat 支付实例
-	app, err := s.GetPaymentApp(ctx)
-	if err != nil {
-		return err
+	refund := &model.Refund{
+		OutRefundNo: s.GenerateRefundNo(),
+		PaymentID:   paymentID,
+		Amount:      amount,
+		Reason:      reason,
+	}
+	if operatorID > 0 {
+		refund.OperatorID = &operatorID
 	}
 
-	// 金额转为分
-	totalCents := int(math.Round(pay.Amount * 100))
-	refundNo := s.GenerateRefundNo()
-
-	// 调用微信退款接口
-	_, err = app.Refund.Refund(ctx, &refundRequest.RequestRefund{
-		TransactionID: pay.TransactionID,
-		OutRefundNo:   refundNo,
-		Reason:        "管理员操作退款",
-		Amount: &refundRequest.RefundAmount{
-			Refund:   totalCents,
-			Total:    totalCents,
-			Currency: "CNY",
-		},
+	// 锁定支付记录后按流水校验可退金额并登记退款中的流水，占住额度防止并发超额退款
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pay, paymentID).Error; err != nil {
+			return errcode.ErrPaymentNotFound
+		}
+		if pay.Status != 1 && pay.Status != 4 {
+			return errcode.ErrRefundFailed
+		}
+
+		locked, err := lockedRefundAmount(tx, paymentID)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 || locked+amount > pay.Amount {
+			return errcode.ErrRefundAmountInvalid
+		}
+		// 捐赠收据和募捐进度均按捐赠全额计算，不支持部分退款
+		if pay.BizType == "donation" && (locked > 0 || amount != pay.Amount) {
+			return errcode.ErrDonationPartialRefund
+		}
+
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		if link != nil {
+			return link(tx, refund)
+		}
+		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("微信退款失败: %w", err)
+		return nil, err
 	}
 
 	now := time.Now()
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 更新支付状态
-		if err := tx.Model(&pay).Updates(map[string]any{
-			"status":    2,
-			"refund_at": &now,
-		}).Error; err != nil {
+	updates := map[string]any{"status": 1, "success_at": &now}
+	if provider != nil {
+		result, err := provider.Refund(ctx, &payprovider.RefundRequest{
+			OrderNo:       pay.OrderNo,
+			TransactionID: pay.TransactionID,
+			OutRefundNo:   refund.OutRefundNo,
+			Reason:        reason,
+			Amount:        amount,
+			Total:         pay.Amount,
+		})
+		if err != nil {
+			// 渠道未受理，关闭流水释放占用的额度
+			s.db.WithContext(ctx).Model(refund).Updates(map[string]any{
+				"status":      2,
+				"fail_reason": err.Error(),
+			})
+			return nil, err
+		}
+
+		// 渠道受理后一般为退款中，到账结果以退款通知为准
+		updates = map[string]any{"refund_id": result.RefundID}
+		switch result.Status {
+		case payprovider.RefundSuccess:
+			updates["status"] = 1
+			updates["success_at"] = &now
+		case payprovider.RefundAbnormal:
+			updates["status"] = 3
+			updates["fail_reason"] = refundAbnormalReason
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(refund).Updates(updates).Error; err != nil {
 			return err
 		}
+		return syncPaymentRefunds(tx, &pay)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
 
-		// 更新关联业务状态
-		if pay.BizType == "registration" {
-			if err := tx.Model(&model.Registration{}).
-				Where("id = ?", pay.BizID).
-				Update("status", 3).Error; err != nil {
-				return err
+// refundAbnormalReason 退款异常时记录的处理提示
+const refundAbnormalReason = "退款到银行卡失败（如卡已注销），请在微信支付商户平台发起异常退款处理"
+
+// HandleRefundNotify 验签并处理支付渠道的退款结果通知，name 为回调地址对应的渠道名称
+// 退款中的流水按通知转为退款成功、退款关闭或退款异常；成功和关闭为终态，重复通知直接忽略
+func (s *PaymentService) HandleRefundNotify(ctx context.Context, name string, r *http.Request) error {
+	provider, err := s.providerFor(ctx, name)
+	if err != nil {
+		return err
+	}
+	n, err := provider.ParseRefundNotify(r)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var refund model.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("out_refund_no = ?", n.OutRefundNo).
+			First(&refund).Error; err != nil {
+			return errcode.ErrRefundNotFound
+		}
+
+		var pay model.Payment
+		if err := tx.First(&pay, refund.PaymentID).Error; err != nil || payprovider.ProviderOf(pay.PayType) != name {
+			return errcode.ErrPaymentNotFound
+		}
+
+		// 幂等处理
+		if refund.Status == 1 || refund.Status == 2 {
+			return nil
+		}
+
+		updates := map[string]any{
+			"refund_id":        n.RefundID,
+			"received_account": n.ReceivedAccount,
+			"notify_data":      n.Raw,
+		}
+		switch n.RefundStatus {
+		case payprovider.RefundSuccess:
+			successAt := time.Now()
+			if n.SuccessTime != nil {
+				successAt = *n.SuccessTime
 			}
+			updates["status"] = 1
+			updates["success_at"] = &successAt
+			updates["fail_reason"] = ""
+		case payprovider.RefundClosed:
+			updates["status"] = 2
+			updates["fail_reason"] = "退款已关闭"
+		case payprovider.RefundAbnormal:
+			updates["status"] = 3
+			updates["fail_reason"] = refundAbnormalReason
+		default:
+			return nil
 		}
+		if err := tx.Model(&refund).Updates(updates).Error; err != nil {
+			return err
+		}
+		return syncPaymentRefunds(tx, &pay)
+	})
+}
+
+// refundSum 按流水汇总指定状态的退款金额
+func refundSum(db *gorm.DB, paymentID int64, statuses ...int) (money.Money, error) {
+	var sum money.Money
+	err := db.Model(&model.Refund{}).
+		Where("payment_id = ? AND status IN ?", paymentID, statuses).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+// lockedRefundAmount 已占用的可退额度（退款中、退款成功和退款异常的流水，已关闭的释放额度）
+func lockedRefundAmount(db *gorm.DB, paymentID int64) (money.Money, error) {
+	return refundSum(db, paymentID, 0, 1, 3)
+}
+
+// syncPaymentRefunds 按退款成功的流水重算支付记录的累计退款金额和状态，首次达到全额退款时同步更新关联业务
+func syncPaymentRefunds(tx *gorm.DB, pay *model.Payment) error {
+	refunded, err := refundSum(tx, pay.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	status := 1 // 已支付
+	switch {
+	case refunded >= pay.Amount:
+		status = 2 // 已退款
+	case refunded > 0:
+		status = 4 // 部分退款
+	}
+
+	updates := map[string]any{
+		"refund_amount": refunded,
+		"status":        status,
+	}
+	if refunded > pay.RefundAmount {
+		updates["refund_at"] = time.Now()
+	}
+	fullyRefunded := status == 2 && pay.Status != 2
+	if err := tx.Model(&model.Payment{}).Where("id = ?", pay.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := syncRefundRegistrations(tx, pay); err != nil {
+		return err
+	}
 
+	if !fullyRefunded {
 		return nil
-	})
+	}
+
+	// 全额退款时更新关联业务状态（仅限以该笔支付确认的业务，自动退还的重复付款不影响报名）
+	switch pay.BizType {
+	case "registration":
+		if err := tx.Model(&model.Registration{}).
+			Where("id = ? AND payment_id = ?", pay.BizID, pay.ID).
+			Update("status", 3).Error; err != nil {
+			return err
+		}
+	case "registration_order":
+		if err := tx.Model(&model.RegistrationOrder{}).
+			Where("id = ? AND payment_id = ?", pay.BizID, pay.ID).
+			Update("status", 3).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Registration{}).
+			Where("order_id = ? AND payment_id = ? AND status = 1", pay.BizID, pay.ID).
+			Update("status", 3).Error; err != nil {
+			return err
+		}
+	case "donation":
+		if err := tx.Model(&model.Donation{}).
+			Where("id = ?", pay.BizID).
+			Update("status", 3).Error; err != nil {
+			return err
+		}
+		if err := voidDonationReceipt(tx, pay.BizID, "捐赠已退款"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncRefundRegistrations 按退款结果更新取消时发起退款的报名：退款成功转为已退款，退款关闭时保持已取消并清零应退金额；
+// 退款中和退款异常时报名保持已取消，异常退款在商户平台处理成功后由退款通知转为已退款。
+// 报名人已全部取消的团体订单，有报名人退款成功后转为已退款
+func syncRefundRegistrations(tx *gorm.DB, pay *model.Payment) error {
+	succeeded := tx.Model(&model.Refund{}).Select("id").Where("payment_id = ? AND status = 1", pay.ID)
+	if err := tx.Model(&model.Registration{}).
+		Where("refund_id IN (?) AND status = 2", succeeded).
+		Update("status", 3).Error; err != nil {
+		return err
+	}
+	if pay.BizType == "registration_order" {
+		if err := tx.Model(&model.RegistrationOrder{}).
+			Where("id = ? AND status = 2", pay.BizID).
+			Where("EXISTS (SELECT 1 FROM registrations WHERE registrations.order_id = registration_orders.id AND registrations.status = 3)").
+			Update("status", 3).Error; err != nil {
+			return err
+		}
+	}
+	closed := tx.Model(&model.Refund{}).Select("id").Where("payment_id = ? AND status = 2", pay.ID)
+	return tx.Model(&model.Registration{}).
+		Where("refund_id IN (?) AND status = 2", closed).
+		Update("refund_amount", 0).Error
+}
+
+// RefundListItem 退款流水列表项
+type RefundListItem struct {
+	model.Refund
+	OrderNo      string `json:"order_no"`
+	BizType      string `json:"biz_type"`
+	OperatorName string `json:"operator_name"`
+}
+
+// ListRefunds 获取退款流水列表（后台管理）
+func (s *PaymentService) ListRefunds(ctx context.Context, page, pageSize int, paymentID int64, status int) ([]RefundListItem, int64, error) {
+	var (
+		list  []RefundListItem
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("refunds").
+		Select("refunds.*, payments.order_no, payments.biz_type, backend_users.username as operator_name").
+		Joins("LEFT JOIN payments ON refunds.payment_id = payments.id").
+		Joins("LEFT JOIN backend_users ON refunds.operator_id = backend_users.id").
+		Where("refunds.deleted_at IS NULL")
+
+	if paymentID > 0 {
+		db = db.Where("refunds.payment_id = ?", paymentID)
+	}
+	if status >= 0 {
+		db = db.Where("refunds.status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("refunds.created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
 }
 
 // GetByOrderNo 根据订单号查询支付记录
@@ -305,8 +841,83 @@ func (s *PaymentService) GetByOrderNo(ctx context.Context, orderNo string) (*mod
 	return &pay, nil
 }
 
-// MarshalTransaction 将交易通知序列化为 JSON 用于存储
-func MarshalTransaction(v any) []byte {
-	data, _ := json.Marshal(v)
-	return data
+// SyncOrder 向支付渠道查询待支付订单的实际状态并同步到本地，用于支付回调丢失时主动补单
+// 非待支付或线下收款的订单直接返回本地记录；查询失败时返回本地记录和错误
+func (s *PaymentService) SyncOrder(ctx context.Context, orderNo string) (*model.Payment, error) {
+	pay, err := s.GetByOrderNo(ctx, orderNo)
+	if err != nil || pay.Status != 0 || pay.PayType == "offline" {
+		return pay, err
+	}
+
+	provider, err := s.providerFor(ctx, payprovider.ProviderOf(pay.PayType))
+	if err != nil {
+		return pay, err
+	}
+	if err := s.syncOrder(ctx, provider, pay); err != nil {
+		return pay, err
+	}
+	return s.GetByOrderNo(ctx, orderNo)
+}
+
+// SyncPendingOrders 批量同步创建超过 1 分钟仍待支付的在线订单，返回状态发生变化的订单数
+// 渠道不可用（如未配置微信支付、未启用模拟支付）的订单跳过
+func (s *PaymentService) SyncPendingOrders(ctx context.Context, limit int) (int, error) {
+	var pays []model.Payment
+	err := s.db.WithContext(ctx).
+		Where("status = 0 AND pay_type <> ? AND created_at < ?", "offline", time.Now().Add(-time.Minute)).
+		Order("created_at").
+		Limit(limit).
+		Find(&pays).Error
+	if err != nil || len(pays) == 0 {
+		return 0, err
+	}
+
+	providers := make(map[string]payprovider.Provider)
+	changed := 0
+	for i := range pays {
+		name := payprovider.ProviderOf(pays[i].PayType)
+		provider, ok := providers[name]
+		if !ok {
+			provider, _ = s.providerFor(ctx, name)
+			providers[name] = provider
+		}
+		if provider == nil {
+			continue
+		}
+		if err := s.syncOrder(ctx, provider, &pays[i]); err != nil {
+			continue
+		}
+		var status int
+		s.db.WithContext(ctx).Model(&model.Payment{}).Where("id = ?", pays[i].ID).Select("status").Scan(&status)
+		if status != 0 {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// syncOrder 按渠道订单状态更新待支付订单，超过有效期仍未支付的先在渠道侧关单再标记为支付失败
+func (s *PaymentService) syncOrder(ctx context.Context, provider payprovider.Provider, pay *model.Payment) error {
+	order, err := provider.Query(ctx, pay.OrderNo)
+	if err != nil {
+		return err
+	}
+
+	if order.TradeState == payprovider.TradeNotPay {
+		if time.Since(pay.CreatedAt) < prepayExpire {
+			return nil
+		}
+		if err := provider.Close(ctx, pay.OrderNo); err != nil {
+			return err
+		}
+		return s.markPayFailed(ctx, pay.ID)
+	}
+	return s.applyOrder(ctx, pay, order)
+}
+
+// markPayFailed 待支付订单标记为支付失败，关联业务保持待支付，用户可重新发起支付
+func (s *PaymentService) markPayFailed(ctx context.Context, paymentID int64) error {
+	return s.db.WithContext(ctx).Model(&model.Payment{}).
+		Where("id = ? AND status = 0", paymentID).
+		Update("status", 3).Error
 }
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/pkg/payprovider"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// 对账差异类型
+const (
+	MismatchMissingLocal = "missing_local" // 账单有记录，本地不存在
+	MismatchAmount       = "amount"        // 金额不一致
+	MismatchStatus       = "status"        // 状态不一致
+)
+
+// ReconcileMismatch 对账差异明细
+type ReconcileMismatch struct {
+	Type          string      `json:"type"`
+	OrderNo       string      `json:"order_no"`
+	RefundNo      string      `json:"refund_no,omitempty"`
+	TransactionID string      `json:"transaction_id,omitempty"`
+	LocalAmount   money.Money `json:"local_amount"`
+	RemoteAmount  money.Money `json:"remote_amount"`
+	LocalStatus   string      `json:"local_status,omitempty"`
+	RemoteStatus  string      `json:"remote_status,omitempty"`
+	Remark        string      `json:"remark"`
+}
+
+// billRecord 微信交易账单中的一行
+type billRecord struct {
+	TransactionID string
+	OrderNo       string
+	TradeState    string // SUCCESS / REFUND / REVOKED
+	Amount        money.Money
+	RefundNo      string
+	RefundAmount  money.Money
+	RefundStatus  string
+}
+
+// ReconcileService 支付对账服务
+type ReconcileService struct {
+	repo       *repository.BaseRepo[model.PaymentReconciliation]
+	db         *gorm.DB
+	paymentSvc *PaymentService
+}
+
+// NewReconcileService 创建支付对账服务
+func NewReconcileService(db *gorm.DB, paymentSvc *PaymentService) *ReconcileService {
+	return &ReconcileService{
+		repo:       repository.NewBaseRepo[model.PaymentReconciliation](db),
+		db:         db,
+		paymentSvc: paymentSvc,
+	}
+}
+
+// List 获取对账报告列表（不含差异明细）
+func (s *ReconcileService) List(ctx context.Context, page, pageSize int) ([]model.PaymentReconciliation, int64, error) {
+	var (
+		list  []model.PaymentReconciliation
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Model(&model.PaymentReconciliation{})
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Omit("mismatches").Order("bill_date DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Get 获取对账报告详情
+func (s *ReconcileService) Get(ctx context.Context, id int64) (*model.PaymentReconciliation, error) {
+	report, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errcode.ErrReconciliationNotFound
+	}
+	return report, nil
+}
+
+// RunScheduled 定时任务的单次执行：同步待支付订单；每天对账起始小时（系统配置 payment.reconcile_hour，默认 10 点，
+// 微信账单已生成）后对前一日账单，失败的账单日 1 小时后重试。未配置微信支付时跳过对账
+func (s *ReconcileService) RunScheduled(ctx context.Context) error {
+	if _, err := s.paymentSvc.SyncPendingOrders(ctx, 100); err != nil {
+		return err
+	}
+
+	configSvc := s.paymentSvc.systemConfigSvc
+	if configSvc.GetString(ctx, "wechat.mch_id") == "" || time.Now().Hour() < configSvc.GetInt(ctx, "payment.reconcile_hour") {
+		return nil
+	}
+	billDate := today().AddDate(0, 0, -1).Format("2006-01-02")
+	if !s.due(ctx, billDate) {
+		return nil
+	}
+	_, err := s.Reconcile(ctx, billDate)
+	return err
+}
+
+// due 判断账单日是否需要对账：未对账，或上次失败且已超过 1 小时
+func (s *ReconcileService) due(ctx context.Context, billDate string) bool {
+	var report model.PaymentReconciliation
+	if err := s.db.WithContext(ctx).Where("bill_date = ?", billDate).First(&report).Error; err != nil {
+		return true
+	}
+	return report.Status == 2 && time.Since(report.UpdatedAt) > time.Hour
+}
+
+// Reconcile 下载指定日期的微信交易账单并与本地支付、退款记录核对，生成对账报告
+func (s *ReconcileService) Reconcile(ctx context.Context, billDate string) (*model.PaymentReconciliation, error) {
+	date, err := time.ParseInLocation("2006-01-02", billDate, time.Local)
+	if err != nil || !date.Before(today()) {
+		return nil, errcode.ErrBillDateInvalid
+	}
+
+	report := model.PaymentReconciliation{BillDate: billDate}
+	err = s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "bill_date"}},
+			DoUpdates: clause.Assignments(map[string]any{"status": 0, "error": "", "updated_at": time.Now()}),
+		}).
+		Create(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Where("bill_date = ?", billDate).First(&report).Error; err != nil {
+		return nil, err
+	}
+
+	records, err := s.downloadTradeBill(ctx, billDate)
+	if err != nil {
+		s.db.WithContext(ctx).Model(&report).Updates(map[string]any{"status": 2, "error": err.Error()})
+		return nil, err
+	}
+
+	mismatches, err := s.compare(ctx, date, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tradeCount, refundCount   int
+		tradeAmount, refundAmount money.Money
+	)
+	for _, r := range records {
+		if r.TradeState == "REFUND" {
+			refundCount++
+			refundAmount += r.RefundAmount
+		} else if r.TradeState == "SUCCESS" {
+			tradeCount++
+			tradeAmount += r.Amount
+		}
+	}
+
+	data, _ := json.Marshal(mismatches)
+	now := time.Now()
+	err = s.db.WithContext(ctx).Model(&report).Updates(map[string]any{
+		"status":         1,
+		"trade_count":    tradeCount,
+		"trade_amount":   tradeAmount,
+		"refund_count":   refundCount,
+		"refund_amount":  refundAmount,
+		"mismatch_count": len(mismatches),
+		"mismatches":     json.RawMessage(data),
+		"error":          "",
+		"finished_at":    &now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, report.ID)
+}
+
+// downloadTradeBill 申请并下载微信交易账单，当日无交易时返回空账单
+func (s *ReconcileService) downloadTradeBill(ctx context.Context, billDate string) ([]billRecord, error) {
+	wechat, err := s.paymentSvc.Wechat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := wechat.TradeBill(ctx, billDate)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return parseTradeBill(bytes.NewReader(data))
+}
+
+// parseTradeBill 解析微信交易账单 CSV：首行为表头，字段值以 ` 开头，末尾两行为汇总
+func parseTradeBill(r io.Reader) ([]billRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析交易账单失败: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	for _, name := range []string{"微信订单号", "商户订单号", "交易状态", "订单金额", "商户退款单号", "申请退款金额", "退款状态"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("解析交易账单失败: 缺少列 %s", name)
+		}
+	}
+
+	var records []billRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析交易账单失败: %w", err)
+		}
+		if len(row) > 0 && strings.HasPrefix(row[0], "总") {
+			break // 汇总区
+		}
+		if len(row) < len(header) {
+			continue
+		}
+
+		field := func(name string) string {
+			return strings.TrimSpace(strings.TrimPrefix(row[col[name]], "`"))
+		}
+		rec := billRecord{
+			TransactionID: field("微信订单号"),
+			OrderNo:       field("商户订单号"),
+			TradeState:    field("交易状态"),
+			RefundNo:      field("商户退款单号"),
+			RefundStatus:  field("退款状态"),
+		}
+		if rec.Amount, err = money.Parse(field("订单金额")); err != nil {
+			return nil, fmt.Errorf("解析交易账单失败: 订单 %s 金额无效", rec.OrderNo)
+		}
+		if rec.TradeState == "REFUND" {
+			if rec.RefundAmount, err = money.Parse(field("申请退款金额")); err != nil {
+				return nil, fmt.Errorf("解析交易账单失败: 退款 %s 金额无效", rec.RefundNo)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// compare 核对账单与本地记录
+// 支付：账单有本地无、金额不一致、账单已支付本地未支付、本地当日已支付账单无记录；
+// 退款：账单有本地无、金额不一致、账单退款成功本地未成功
+func (s *ReconcileService) compare(ctx context.Context, date time.Time, records []billRecord) ([]ReconcileMismatch, error) {
+	db := s.db.WithContext(ctx)
+	mismatches := []ReconcileMismatch{}
+	billed := make(map[string]bool)
+
+	for _, r := range records {
+		if r.TradeState == "REFUND" {
+			var refund model.Refund
+			if err := db.Where("out_refund_no = ?", r.RefundNo).First(&refund).Error; err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, err
+				}
+				mismatches = append(mismatches, ReconcileMismatch{
+					Type: MismatchMissingLocal, OrderNo: r.OrderNo, RefundNo: r.RefundNo, TransactionID: r.TransactionID,
+					RemoteAmount: r.RefundAmount, RemoteStatus: r.RefundStatus, Remark: "账单有退款，本地无退款流水",
+				})
+				continue
+			}
+			if refund.Amount != r.RefundAmount {
+				mismatches = append(mismatches, ReconcileMismatch{
+					Type: MismatchAmount, OrderNo: r.OrderNo, RefundNo: r.RefundNo, TransactionID: r.TransactionID,
+					LocalAmount: refund.Amount, RemoteAmount: r.RefundAmount, Remark: "退款金额不一致",
+				})
+			}
+			if r.RefundStatus == "SUCCESS" && refund.Status != 1 {
+				mismatches = append(mismatches, ReconcileMismatch{
+					Type: MismatchStatus, OrderNo: r.OrderNo, RefundNo: r.RefundNo, TransactionID: r.TransactionID,
+					LocalAmount: refund.Amount, RemoteAmount: r.RefundAmount,
+					LocalStatus: refundStatusText(refund.Status), RemoteStatus: r.RefundStatus, Remark: "账单退款成功，本地未成功",
+				})
+			}
+			continue
+		}
+
+		if r.TradeState != "SUCCESS" {
+			continue
+		}
+		billed[r.OrderNo] = true
+
+		var pay model.Payment
+		if err := db.Where("order_no = ?", r.OrderNo).First(&pay).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			mismatches = append(mismatches, ReconcileMismatch{
+				Type: MismatchMissingLocal, OrderNo: r.OrderNo, TransactionID: r.TransactionID,
+				RemoteAmount: r.Amount, RemoteStatus: r.TradeState, Remark: "账单有支付，本地无支付记录",
+			})
+			continue
+		}
+		if pay.Amount != r.Amount {
+			mismatches = append(mismatches, ReconcileMismatch{
+				Type: MismatchAmount, OrderNo: r.OrderNo, TransactionID: r.TransactionID,
+				LocalAmount: pay.Amount, RemoteAmount: r.Amount, Remark: "支付金额不一致",
+			})
+		}
+		if pay.Status != 1 && pay.Status != 2 && pay.Status != 4 {
+			mismatches = append(mismatches, ReconcileMismatch{
+				Type: MismatchStatus, OrderNo: r.OrderNo, TransactionID: r.TransactionID,
+				LocalAmount: pay.Amount, RemoteAmount: r.Amount,
+				LocalStatus: paymentStatusText(pay.Status), RemoteStatus: r.TradeState, Remark: "账单已支付，本地未支付",
+			})
+		}
+	}
+
+	// 本地当日微信支付（含小程序、扫码和 H5）成功但账单中没有的交易
+	var paid []model.Payment
+	err := db.Where("pay_type IN ? AND status IN (1,2,4) AND paid_at >= ? AND paid_at < ?",
+		payprovider.WechatPayTypes, date, date.AddDate(0, 0, 1)).
+		Find(&paid).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, pay := range paid {
+		if billed[pay.OrderNo] {
+			continue
+		}
+		mismatches = append(mismatches, ReconcileMismatch{
+			Type: MismatchStatus, OrderNo: pay.OrderNo, TransactionID: pay.TransactionID,
+			LocalAmount: pay.Amount, LocalStatus: paymentStatusText(pay.Status), Remark: "本地已支付，账单无此交易",
+		})
+	}
+
+	return mismatches, nil
+}
+
+// today 本地时区当天零点
+func today() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func paymentStatusText(status int) string {
+	switch status {
+	case 0:
+		return "待支付"
+	case 1:
+		return "已支付"
+	case 2:
+		return "已退款"
+	case 3:
+		return "支付失败"
+	case 4:
+		return "部分退款"
+	}
+	return ""
+}
+
+func refundStatusText(status int) string {
+	switch status {
+	case 0:
+		return "退款中"
+	case 1:
+		return "退款成功"
+	case 2:
+		return "退款关闭"
+	case 3:
+		return "退款异常"
+	}
+	return ""
+}
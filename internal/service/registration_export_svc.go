@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/mask"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// extraColumnPrefix 附加信息列的 key 前缀，如 extra.emergency_contact
+const extraColumnPrefix = "extra."
+
+// ExportColumn 可导出的列
+type ExportColumn struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+}
+
+// registrationExportColumns 报名导出的标准列
+var registrationExportColumns = []ExportColumn{
+	{Key: "id", Title: "报名ID"},
+	{Key: "activity_title", Title: "活动"},
+	{Key: "ticket_name", Title: "票种"},
+	{Key: "name", Title: "姓名"},
+	{Key: "phone", Title: "手机号"},
+	{Key: "id_card", Title: "证件号"},
+	{Key: "amount", Title: "金额"},
+	{Key: "status", Title: "状态"},
+	{Key: "created_at", Title: "报名时间"},
+	{Key: "checked_in_at", Title: "签到时间"},
+	{Key: "review_reason", Title: "审核意见"},
+}
+
+// registrationStatusText 报名状态文字
+var registrationStatusText = map[int]string{
+	0: "待支付",
+	1: "已确认",
+	2: "已取消",
+	3: "已退款",
+	4: "待审核",
+	5: "审核未通过",
+}
+
+// RegistrationExportOptions 报名导出条件
+type RegistrationExportOptions struct {
+	ActivityID int64
+	Status     int      // -1 表示全部
+	Columns    []string // 为空时导出全部标准列和附加信息列
+	Masked     bool     // 是否对手机号和证件号脱敏
+}
+
+// exportRow 导出查询的行结构
+type exportRow struct {
+	ID            int64
+	ActivityTitle string
+	TicketName    string
+	Name          string
+	Phone         string
+	IDCard        string
+	ExtraInfo     json.RawMessage
+	Amount        money.Money
+	Status        int
+	CreatedAt     time.Time
+	CheckedInAt   *time.Time
+	ReviewReason  string
+}
+
+// exportQuery 构造导出查询
+func (s *RegistrationService) exportQuery(ctx context.Context, opts *RegistrationExportOptions) *gorm.DB {
+	db := s.db.WithContext(ctx).Table("registrations").
+		Joins("LEFT JOIN activities ON registrations.activity_id = activities.id").
+		Joins("LEFT JOIN activity_tickets ON registrations.ticket_id = activity_tickets.id").
+		Where("registrations.deleted_at IS NULL")
+
+	if opts.ActivityID > 0 {
+		db = db.Where("registrations.activity_id = ?", opts.ActivityID)
+	}
+	if opts.Status >= 0 {
+		db = db.Where("registrations.status = ?", opts.Status)
+	}
+	return db
+}
+
+// ExportColumns 获取可导出的列：标准列加上报名附加信息中出现过的全部 key
+func (s *RegistrationService) ExportColumns(ctx context.Context, opts *RegistrationExportOptions) ([]ExportColumn, error) {
+	rows, err := s.exportQuery(ctx, opts).
+		Select("registrations.extra_info").
+		Where("registrations.extra_info IS NOT NULL").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		for key := range flattenExtraInfo(raw) {
+			seen[key] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	columns := append([]ExportColumn{}, registrationExportColumns...)
+	for _, key := range keys {
+		columns = append(columns, ExportColumn{Key: extraColumnPrefix + key, Title: key})
+	}
+	return columns, nil
+}
+
+// Export 按条件逐行导出报名记录，每行通过 emit 回调写出，返回导出的数据行数
+// 第一次回调为表头；数据行直接从数据库游标读取，不在内存中缓存
+func (s *RegistrationService) Export(ctx context.Context, opts *RegistrationExportOptions, emit func(row []string) error) (int, error) {
+	var (
+		columns []ExportColumn
+		err     error
+	)
+	if len(opts.Columns) > 0 {
+		columns, err = selectExportColumns(opts.Columns)
+	} else {
+		columns, err = s.ExportColumns(ctx, opts)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Title
+	}
+	if err := emit(header); err != nil {
+		return 0, err
+	}
+
+	rows, err := s.exportQuery(ctx, opts).
+		Select("registrations.id, activities.title as activity_title, activity_tickets.name as ticket_name, registrations.name, registrations.phone, registrations.id_card, registrations.extra_info, registrations.amount, registrations.status, registrations.created_at, registrations.checked_in_at, registrations.review_reason").
+		Order("registrations.id").
+		Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var r exportRow
+		if err := s.db.ScanRows(rows, &r); err != nil {
+			return count, err
+		}
+
+		extra := flattenExtraInfo(r.ExtraInfo)
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = r.value(col.Key, extra, opts.Masked)
+		}
+		if err := emit(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// selectExportColumns 校验并返回请求的导出列
+func selectExportColumns(keys []string) ([]ExportColumn, error) {
+	standard := make(map[string]ExportColumn, len(registrationExportColumns))
+	for _, col := range registrationExportColumns {
+		standard[col.Key] = col
+	}
+
+	columns := make([]ExportColumn, 0, len(keys))
+	for _, key := range keys {
+		if col, ok := standard[key]; ok {
+			columns = append(columns, col)
+			continue
+		}
+		if name, ok := strings.CutPrefix(key, extraColumnPrefix); ok && name != "" {
+			columns = append(columns, ExportColumn{Key: key, Title: name})
+			continue
+		}
+		return nil, fmt.Errorf("%w: %s", errcode.ErrExportColumnInvalid, key)
+	}
+	return columns, nil
+}
+
+// value 获取行中指定列的文本值
+func (r *exportRow) value(key string, extra map[string]string, masked bool) string {
+	switch key {
+	case "id":
+		return strconv.FormatInt(r.ID, 10)
+	case "activity_title":
+		return r.ActivityTitle
+	case "ticket_name":
+		return r.TicketName
+	case "name":
+		return r.Name
+	case "phone":
+		if masked {
+			return mask.Phone(r.Phone)
+		}
+		return r.Phone
+	case "id_card":
+		if masked {
+			return mask.IDCard(r.IDCard)
+		}
+		return r.IDCard
+	case "amount":
+		return r.Amount.String()
+	case "status":
+		return registrationStatusText[r.Status]
+	case "created_at":
+		return r.CreatedAt.Format("2006-01-02 15:04:05")
+	case "checked_in_at":
+		if r.CheckedInAt == nil {
+			return ""
+		}
+		return r.CheckedInAt.Format("2006-01-02 15:04:05")
+	case "review_reason":
+		return r.ReviewReason
+	}
+	return extra[strings.TrimPrefix(key, extraColumnPrefix)]
+}
+
+// flattenExtraInfo 将报名附加信息展开为一层 key/value，嵌套对象以 "." 连接 key
+// 数组和非对象的 JSON 值按原始 JSON 文本输出
+func flattenExtraInfo(raw []byte) map[string]string {
+	out := make(map[string]string)
+	if len(raw) == 0 {
+		return out
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return out
+	}
+	flattenInto(out, "", obj)
+	return out
+}
+
+func flattenInto(out map[string]string, prefix string, obj map[string]any) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flattenInto(out, key, val)
+		case string:
+			out[key] = val
+		case nil:
+			out[key] = ""
+		default:
+			b, _ := json.Marshal(val)
+			out[key] = string(b)
+		}
+	}
+}
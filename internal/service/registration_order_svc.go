@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// RegistrationOrderService 团体报名订单服务
+type RegistrationOrderService struct {
+	repo       *repository.BaseRepo[model.RegistrationOrder]
+	db         *gorm.DB
+	paymentSvc *PaymentService
+}
+
+// NewRegistrationOrderService 创建团体报名订单服务
+func NewRegistrationOrderService(db *gorm.DB, paymentSvc *PaymentService) *RegistrationOrderService {
+	return &RegistrationOrderService{
+		repo:       repository.NewBaseRepo[model.RegistrationOrder](db),
+		db:         db,
+		paymentSvc: paymentSvc,
+	}
+}
+
+// CreateOrderRequest 创建团体报名请求
+type CreateOrderRequest struct {
+	ActivityID   int64                `json:"activity_id" binding:"required"`
+	Participants []ParticipantRequest `json:"participants" binding:"required,min=1,max=20,dive"`
+}
+
+// Create 创建团体报名订单（小程序端调用）
+// 所有报名人在同一事务中校验名额，任一不满足则整体失败
+func (s *RegistrationOrderService) Create(ctx context.Context, userID int64, req *CreateOrderRequest) (*model.RegistrationOrder, error) {
+	var order model.RegistrationOrder
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := lockOpenActivity(tx, req.ActivityID)
+		if err != nil {
+			return err
+		}
+
+		order = model.RegistrationOrder{
+			ActivityID: req.ActivityID,
+			UserID:     userID,
+			Status:     0,
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		regs, err := createParticipants(tx, activity, userID, &order.ID, req.Participants)
+		if err != nil {
+			return err
+		}
+
+		var total money.Money
+		for _, r := range regs {
+			total += r.Amount
+		}
+		order.TotalAmount = total
+		order.Participants = regs
+
+		// 全部免费时直接确认
+		if order.TotalAmount == 0 {
+			order.Status = 1
+		}
+
+		return tx.Model(&order).Updates(map[string]any{
+			"total_amount": order.TotalAmount,
+			"status":       order.Status,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Get 获取报名订单详情（含报名人）
+func (s *RegistrationOrderService) Get(ctx context.Context, id int64) (*model.RegistrationOrder, error) {
+	var order model.RegistrationOrder
+	err := s.db.WithContext(ctx).
+		Preload("Activity").
+		Preload("Participants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		First(&order, id).Error
+	if err != nil {
+		return nil, errcode.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+// CheckPayable 校验报名订单是否可以发起支付（存在待审核报名人时不可支付）
+func (s *RegistrationOrderService) CheckPayable(order *model.RegistrationOrder) error {
+	if order.Status != 0 {
+		return errcode.ErrOrderStatusInvalid
+	}
+	for _, p := range order.Participants {
+		if p.Status == 4 {
+			return errcode.ErrRegistrationPendingReview
+		}
+	}
+	return nil
+}
+
+// CancelParticipants 取消订单中的部分或全部报名人
+// registrationIDs 为空时取消全部报名人；已支付订单按活动退款规则对被取消报名人原路部分退款，退款成功后报名人转为已退款。
+// 先以条件更新占住报名人的取消状态再发起退款，并发取消同一报名人时只有一方能占住，退款失败时恢复原状态
+func (s *RegistrationOrderService) CancelParticipants(ctx context.Context, userID, orderID int64, registrationIDs []int64) error {
+	var order model.RegistrationOrder
+	if err := s.db.WithContext(ctx).First(&order, orderID).Error; err != nil {
+		return errcode.ErrOrderNotFound
+	}
+
+	// 校验是否是本人的订单
+	if order.UserID != userID {
+		return errcode.ErrForbidden
+	}
+
+	if order.Status != 0 && order.Status != 1 {
+		return errcode.ErrOrderStatusInvalid
+	}
+
+	// 待支付订单的金额将变化，先关闭待支付的支付订单，防止按原金额完成支付
+	if order.Status == 0 {
+		if err := s.paymentSvc.closePendingBiz(ctx, "registration_order", orderID); err != nil {
+			return err
+		}
+	}
+
+	query := s.db.WithContext(ctx).
+		Where("order_id = ? AND status IN (0,1,4)", orderID)
+	if len(registrationIDs) > 0 {
+		query = query.Where("id IN ?", registrationIDs)
+	}
+	var regs []model.Registration
+	if err := query.Find(&regs).Error; err != nil {
+		return err
+	}
+	if len(regs) == 0 {
+		return errcode.ErrRegistrationCancelled
+	}
+
+	var activity model.Activity
+	if err := s.db.WithContext(ctx).First(&activity, order.ActivityID).Error; err != nil {
+		return errcode.ErrNotFound
+	}
+
+	// 已支付订单按活动退款规则计算每名报名人的可退金额
+	paid := order.Status == 1 && order.PaymentID != nil
+	now := time.Now()
+	ids := make([]int64, len(regs))
+	var refundTotal money.Money
+	refunds := make(map[int64]money.Money, len(regs))
+	for i, r := range regs {
+		ids[i] = r.ID
+		if paid {
+			refunds[r.ID] = CalcRefundAmount(&activity, r.Amount, now)
+			refundTotal += refunds[r.ID]
+		}
+	}
+
+	// 以条件更新占住取消状态，任一报名人已被并发取消时整体放弃，防止重复退款
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Registration{}).
+			Where("id IN ? AND order_id = ? AND status IN (0,1,4)", ids, orderID).
+			Update("status", 2)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != int64(len(regs)) {
+			return errcode.ErrRegistrationCancelled
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 发起部分退款，报名人保持已取消并关联退款流水，退款成功后转为已退款（见 syncRefundRegistrations）；
+	// 发起失败时恢复报名人原状态
+	if refundTotal > 0 {
+		_, err := s.paymentSvc.refund(ctx, *order.PaymentID, refundTotal, "团体报名取消报名人", 0, func(tx *gorm.DB, refund *model.Refund) error {
+			for _, r := range regs {
+				if refunds[r.ID] == 0 {
+					continue
+				}
+				if err := tx.Model(&model.Registration{}).
+					Where("id = ?", r.ID).
+					Updates(map[string]any{
+						"refund_id":     refund.ID,
+						"refund_amount": refunds[r.ID],
+					}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, r := range regs {
+					if err := tx.Model(&model.Registration{}).
+						Where("id = ? AND status = 2", r.ID).
+						Updates(map[string]any{
+							"status":        r.Status,
+							"refund_id":     nil,
+							"refund_amount": 0,
+						}).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			return err
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 按剩余报名人重新汇总订单金额，避免并发取消不同报名人时以过期的订单金额相减
+		var remaining struct {
+			Count int64
+			Total money.Money
+		}
+		if err := tx.Model(&model.Registration{}).
+			Where("order_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", orderID).
+			Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+			Scan(&remaining).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"total_amount": remaining.Total,
+		}
+		switch {
+		case remaining.Count == 0:
+			// 有报名人已退款成功时为已退款，否则为已取消，退款到账后再转为已退款
+			var refunded int64
+			if err := tx.Model(&model.Registration{}).
+				Where("order_id = ? AND status = 3", orderID).
+				Count(&refunded).Error; err != nil {
+				return err
+			}
+			updates["status"] = 2 // 已取消
+			if refunded > 0 {
+				updates["status"] = 3 // 已退款
+			}
+		case order.Status == 0 && remaining.Total == 0:
+			// 待支付订单只剩免费报名人时无需支付，直接确认（同创建订单时全部免费的处理）
+			updates["status"] = 1
+		}
+		return tx.Model(&order).Updates(updates).Error
+	})
+}
+
+// GetByUser 获取用户的报名订单列表（小程序端）
+func (s *RegistrationOrderService) GetByUser(ctx context.Context, userID int64, page, pageSize int) ([]model.RegistrationOrder, int64, error) {
+	var (
+		list  []model.RegistrationOrder
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Model(&model.RegistrationOrder{}).Where("user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Preload("Activity").Preload("Participants").
+		Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
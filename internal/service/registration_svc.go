@@ -6,34 +6,52 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/zzhtl/go-mountain/internal/model"
 	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/mask"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
 	"github.com/zzhtl/go-mountain/internal/repository"
 )
 
 // RegistrationService 报名服务
 type RegistrationService struct {
-	repo *repository.BaseRepo[model.Registration]
-	db   *gorm.DB
+	repo       *repository.BaseRepo[model.Registration]
+	db         *gorm.DB
+	paymentSvc *PaymentService
 }
 
 // NewRegistrationService 创建报名服务
-func NewRegistrationService(db *gorm.DB) *RegistrationService {
+func NewRegistrationService(db *gorm.DB, paymentSvc *PaymentService) *RegistrationService {
 	return &RegistrationService{
-		repo: repository.NewBaseRepo[model.Registration](db),
-		db:   db,
+		repo:       repository.NewBaseRepo[model.Registration](db),
+		db:         db,
+		paymentSvc: paymentSvc,
 	}
 }
 
+// GetDB 返回数据库连接（供 handler 做细粒度权限判断使用）
+func (s *RegistrationService) GetDB() *gorm.DB {
+	return s.db
+}
+
 // RegistrationListItem 报名列表项（含活动和用户信息）
 type RegistrationListItem struct {
 	model.Registration
 	ActivityTitle string `json:"activity_title"`
+	TicketName    string `json:"ticket_name"`
 	UserName      string `json:"user_name"`
 	UserPhone     string `json:"user_phone"`
 }
 
+// MaskSensitive 对手机号和证件号脱敏，用于无查看敏感信息权限的后台用户
+func (item *RegistrationListItem) MaskSensitive() {
+	item.Phone = mask.Phone(item.Phone)
+	item.IDCard = mask.IDCard(item.IDCard)
+	item.UserPhone = mask.Phone(item.UserPhone)
+}
+
 // List 获取报名列表（后台管理）
 func (s *RegistrationService) List(ctx context.Context, page, pageSize int, activityID int64, status int) ([]RegistrationListItem, int64, error) {
 	var (
@@ -42,8 +60,9 @@ func (s *RegistrationService) List(ctx context.Context, page, pageSize int, acti
 	)
 
 	db := s.db.WithContext(ctx).Table("registrations").
-		Select("registrations.*, activities.title as activity_title").
+		Select("registrations.*, activities.title as activity_title, activity_tickets.name as ticket_name").
 		Joins("LEFT JOIN activities ON registrations.activity_id = activities.id").
+		Joins("LEFT JOIN activity_tickets ON registrations.ticket_id = activity_tickets.id").
 		Where("registrations.deleted_at IS NULL")
 
 	if activityID > 0 {
@@ -66,8 +85,9 @@ func (s *RegistrationService) List(ctx context.Context, page, pageSize int, acti
 func (s *RegistrationService) Get(ctx context.Context, id int64) (*RegistrationListItem, error) {
 	var item RegistrationListItem
 	err := s.db.WithContext(ctx).Table("registrations").
-		Select("registrations.*, activities.title as activity_title").
+		Select("registrations.*, activities.title as activity_title, activity_tickets.name as ticket_name").
 		Joins("LEFT JOIN activities ON registrations.activity_id = activities.id").
+		Joins("LEFT JOIN activity_tickets ON registrations.ticket_id = activity_tickets.id").
 		Where("registrations.id = ? AND registrations.deleted_at IS NULL", id).
 		First(&item).Error
 	if err != nil {
@@ -76,22 +96,68 @@ func (s *RegistrationService) Get(ctx context.Context, id int64) (*RegistrationL
 	return &item, nil
 }
 
+// ParticipantRequest 报名人信息
+type ParticipantRequest struct {
+	TicketID  int64           `json:"ticket_id"` // 活动配置了票种时必填
+	Name      string          `json:"name" binding:"required"`
+	Phone     string          `json:"phone" binding:"required"`
+	IDCard    string          `json:"id_card"`
+	ExtraInfo json.RawMessage `json:"extra_info"`
+}
+
 // CreateRegistrationRequest 创建报名请求
 type CreateRegistrationRequest struct {
-	ActivityID int64           `json:"activity_id" binding:"required"`
-	Name       string          `json:"name" binding:"required"`
-	Phone      string          `json:"phone" binding:"required"`
-	IDCard     string          `json:"id_card"`
-	ExtraInfo  json.RawMessage `json:"extra_info"`
+	ActivityID int64 `json:"activity_id" binding:"required"`
+	ParticipantRequest
 }
 
 // Create 创建报名（小程序端调用）
 func (s *RegistrationService) Create(ctx context.Context, userID int64, req *CreateRegistrationRequest) (*model.Registration, error) {
-	// 查询活动
+	var reg *model.Registration
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := lockOpenActivity(tx, req.ActivityID)
+		if err != nil {
+			return err
+		}
+
+		// 校验是否重复报名
+		var existCount int64
+		tx.Model(&model.Registration{}).
+			Where("activity_id = ? AND user_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", req.ActivityID, userID).
+			Count(&existCount)
+		if existCount > 0 {
+			return errcode.ErrAlreadyRegistered
+		}
+
+		regs, err := createParticipants(tx, activity, userID, nil, []ParticipantRequest{req.ParticipantRequest})
+		if err != nil {
+			return err
+		}
+		reg = &regs[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// lockActivity 在事务中锁定活动行
+// 锁定活动行使同一活动的名额校验串行化（SQLite 下忽略行锁，依赖库级写锁）
+func lockActivity(tx *gorm.DB, activityID int64) (*model.Activity, error) {
 	var activity model.Activity
-	if err := s.db.WithContext(ctx).First(&activity, req.ActivityID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&activity, activityID).Error; err != nil {
 		return nil, errcode.ErrNotFound
 	}
+	return &activity, nil
+}
+
+// lockOpenActivity 在事务中锁定活动行并校验是否开放报名
+func lockOpenActivity(tx *gorm.DB, activityID int64) (*model.Activity, error) {
+	activity, err := lockActivity(tx, activityID)
+	if err != nil {
+		return nil, err
+	}
 
 	// 校验活动状态
 	if activity.Status != 1 {
@@ -107,50 +173,123 @@ func (s *RegistrationService) Create(ctx context.Context, userID int64, req *Cre
 		return nil, errcode.ErrActivityNotOpen
 	}
 
+	return activity, nil
+}
+
+// createParticipants 在事务中校验名额、票种和重复报名后批量创建报名记录
+// 所有报名人一起校验，任一不满足则整体失败
+func createParticipants(tx *gorm.DB, activity *model.Activity, userID int64, orderID *int64, participants []ParticipantRequest) ([]model.Registration, error) {
 	// 校验人数上限
 	if activity.MaxParticipants > 0 {
 		var count int64
-		s.db.WithContext(ctx).Model(&model.Registration{}).
-			Where("activity_id = ? AND status IN (0,1) AND deleted_at IS NULL", req.ActivityID).
+		tx.Model(&model.Registration{}).
+			Where("activity_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", activity.ID).
 			Count(&count)
-		if count >= int64(activity.MaxParticipants) {
+		if count+int64(len(participants)) > int64(activity.MaxParticipants) {
 			return nil, errcode.ErrActivityFull
 		}
 	}
 
-	// 校验是否重复报名
-	var existCount int64
-	s.db.WithContext(ctx).Model(&model.Registration{}).
-		Where("activity_id = ? AND user_id = ? AND status IN (0,1) AND deleted_at IS NULL", req.ActivityID, userID).
-		Count(&existCount)
-	if existCount > 0 {
-		return nil, errcode.ErrAlreadyRegistered
+	// 校验报名人是否重复
+	seen := make(map[string]bool, len(participants))
+	for _, p := range participants {
+		key := p.IDCard
+		if key == "" {
+			key = p.Name + "|" + p.Phone
+		}
+		if seen[key] {
+			return nil, errcode.ErrDuplicateParticipant
+		}
+		seen[key] = true
+
+		if err := checkDuplicateParticipant(tx, activity.ID, &p, 0); err != nil {
+			return nil, err
+		}
 	}
 
-	// 创建报名记录
-	reg := &model.Registration{
-		ActivityID: req.ActivityID,
-		UserID:     userID,
-		Name:       req.Name,
-		Phone:      req.Phone,
-		IDCard:     req.IDCard,
-		ExtraInfo:  req.ExtraInfo,
-		Status:     0, // 待支付（免费活动也先设为0，后面由支付流程或直接确认）
+	// 校验票种（配置了票种的活动按票种计价和限额）
+	ticketSvc := NewActivityTicketService(tx)
+	prices := make(map[int64]money.Money)
+	if ticketSvc.HasTickets(tx.Statement.Context, activity.ID) {
+		quantities := make(map[int64]int)
+		for _, p := range participants {
+			if p.TicketID == 0 {
+				return nil, errcode.ErrTicketRequired
+			}
+			quantities[p.TicketID]++
+		}
+		for ticketID, qty := range quantities {
+			ticket, err := ticketSvc.CheckAvailable(tx.Statement.Context, activity.ID, ticketID, qty)
+			if err != nil {
+				return nil, err
+			}
+			prices[ticketID] = ticket.Price
+		}
 	}
 
-	// 免费活动直接确认报名
-	if activity.Price == 0 {
-		reg.Status = 1 // 已确认
+	regs := make([]model.Registration, 0, len(participants))
+	for _, p := range participants {
+		amount := activity.Price
+		var ticketID *int64
+		if price, ok := prices[p.TicketID]; ok {
+			amount = price
+			id := p.TicketID
+			ticketID = &id
+		}
+
+		reg := model.Registration{
+			ActivityID: activity.ID,
+			TicketID:   ticketID,
+			UserID:     userID,
+			OrderID:    orderID,
+			Name:       p.Name,
+			Phone:      p.Phone,
+			IDCard:     p.IDCard,
+			ExtraInfo:  p.ExtraInfo,
+			Amount:     amount,
+			Status:     0, // 待支付（免费活动也先设为0，后面由支付流程或直接确认）
+		}
+
+		// 需审核的活动先进入待审核；免费活动（或免费票种）直接确认报名
+		if activity.RequiresApproval {
+			reg.Status = 4 // 待审核
+		} else if amount == 0 {
+			reg.Status = 1 // 已确认
+		}
+
+		if err := tx.Create(&reg).Error; err != nil {
+			return nil, err
+		}
+		regs = append(regs, reg)
 	}
 
-	if err := s.repo.Create(ctx, reg); err != nil {
-		return nil, err
+	return regs, nil
+}
+
+// checkDuplicateParticipant 校验报名人在活动内是否已有有效报名
+// 同一活动内按证件号判断，无证件号时按姓名+手机号；excludeID 为编辑时排除的自身报名
+func checkDuplicateParticipant(tx *gorm.DB, activityID int64, p *ParticipantRequest, excludeID int64) error {
+	query := tx.Model(&model.Registration{}).
+		Where("activity_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", activityID)
+	if p.IDCard != "" {
+		query = query.Where("id_card = ?", p.IDCard)
+	} else {
+		query = query.Where("name = ? AND phone = ?", p.Name, p.Phone)
+	}
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
 	}
 
-	return reg, nil
+	var existCount int64
+	query.Count(&existCount)
+	if existCount > 0 {
+		return errcode.ErrDuplicateParticipant
+	}
+	return nil
 }
 
 // Cancel 取消报名
+// 已支付的报名按活动退款规则自动原路退款，退款成功后报名转为已退款，发起退款失败时报名保持原状态
 func (s *RegistrationService) Cancel(ctx context.Context, id int64, userID int64) error {
 	var reg model.Registration
 	if err := s.db.WithContext(ctx).First(&reg, id).Error; err != nil {
@@ -162,11 +301,410 @@ func (s *RegistrationService) Cancel(ctx context.Context, id int64, userID int64
 		return errcode.ErrForbidden
 	}
 
-	if reg.Status == 2 {
+	return s.cancel(ctx, &reg, false, "用户取消报名")
+}
+
+// cancel 取消报名并按需退款，fullRefund 为 true 时忽略退款规则全额退款
+func (s *RegistrationService) cancel(ctx context.Context, reg *model.Registration, fullRefund bool, reason string) error {
+	id := reg.ID
+
+	// 团体报名需通过报名订单取消，以便同步订单金额和退款
+	if reg.OrderID != nil {
+		return errcode.ErrRegistrationInOrder
+	}
+
+	if reg.Status == 2 || reg.Status == 3 {
+		return errcode.ErrRegistrationCancelled
+	}
+
+	if reg.Status == 5 {
+		return errcode.ErrRegistrationRejected
+	}
+
+	// 未支付或免费报名直接取消，待支付的先关闭支付订单，防止取消后仍能完成支付
+	if reg.Status != 1 || reg.PaymentID == nil || reg.Amount == 0 {
+		if reg.Status == 0 {
+			if err := s.paymentSvc.closePendingBiz(ctx, "registration", id); err != nil {
+				return err
+			}
+		}
+		res := s.db.WithContext(ctx).Model(&model.Registration{}).
+			Where("id = ? AND status = ?", id, reg.Status).
+			Update("status", 2)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errcode.ErrRegistrationCancelled
+		}
+		return nil
+	}
+
+	var activity model.Activity
+	if err := s.db.WithContext(ctx).First(&activity, reg.ActivityID).Error; err != nil {
+		return errcode.ErrNotFound
+	}
+	refund := CalcRefundAmount(&activity, reg.Amount, time.Now())
+	if fullRefund {
+		refund = reg.Amount
+	}
+
+	// 先以条件更新占住取消状态，防止并发取消重复退款
+	res := s.db.WithContext(ctx).Model(&model.Registration{}).
+		Where("id = ? AND status = 1", id).
+		Update("status", 2)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
 		return errcode.ErrRegistrationCancelled
 	}
 
-	return s.repo.Update(ctx, id, map[string]any{"status": 2})
+	if refund == 0 {
+		return nil // 超出退款期限，不退款
+	}
+
+	// 报名保持已取消并关联退款流水，退款成功后转为已退款（见 syncRefundRegistrations）
+	_, err := s.paymentSvc.refund(ctx, *reg.PaymentID, refund, reason, 0, func(tx *gorm.DB, r *model.Refund) error {
+		return tx.Model(&model.Registration{}).Where("id = ?", id).Updates(map[string]any{
+			"refund_id":     r.ID,
+			"refund_amount": refund,
+		}).Error
+	})
+	if err != nil {
+		s.repo.Update(ctx, id, map[string]any{
+			"status":        1,
+			"refund_id":     nil,
+			"refund_amount": 0,
+		})
+		return err
+	}
+	return nil
+}
+
+// RefundPreview 取消报名前预览可退金额
+type RefundPreview struct {
+	Amount       money.Money `json:"amount"`
+	RefundRate   int         `json:"refund_rate"`
+	RefundAmount money.Money `json:"refund_amount"`
+}
+
+// PreviewRefund 按当前时间和活动退款规则预览取消报名的可退金额
+func (s *RegistrationService) PreviewRefund(ctx context.Context, id int64, userID int64) (*RefundPreview, error) {
+	var reg model.Registration
+	if err := s.db.WithContext(ctx).First(&reg, id).Error; err != nil {
+		return nil, errcode.ErrRegistrationNotFound
+	}
+
+	if reg.UserID != userID {
+		return nil, errcode.ErrForbidden
+	}
+
+	preview := &RefundPreview{Amount: reg.Amount}
+	if reg.Status != 1 || reg.PaymentID == nil {
+		return preview, nil
+	}
+
+	var activity model.Activity
+	if err := s.db.WithContext(ctx).First(&activity, reg.ActivityID).Error; err != nil {
+		return nil, errcode.ErrNotFound
+	}
+	now := time.Now()
+	preview.RefundRate = RefundRate(&activity, now)
+	preview.RefundAmount = CalcRefundAmount(&activity, reg.Amount, now)
+	return preview, nil
+}
+
+// RefundRate 按活动退款规则计算当前取消报名的可退比例（百分比）
+func RefundRate(activity *model.Activity, now time.Time) int {
+	remaining := activity.StartTime.Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+
+	day := 24 * time.Hour
+	switch {
+	case remaining >= time.Duration(activity.RefundFullDays)*day:
+		return 100
+	case remaining >= time.Duration(activity.RefundPartialDays)*day:
+		return activity.RefundPartialRate
+	default:
+		return 0
+	}
+}
+
+// CalcRefundAmount 按退款比例计算可退金额（向下取整到分，避免超额退款）
+func CalcRefundAmount(activity *model.Activity, amount money.Money, now time.Time) money.Money {
+	return amount.Percent(RefundRate(activity, now))
+}
+
+// CheckRegistrationPayable 校验报名是否可以发起支付
+// 需审核的活动在审核通过后状态才会变为待支付
+func CheckRegistrationPayable(status int) error {
+	switch status {
+	case 0:
+		return nil
+	case 4:
+		return errcode.ErrRegistrationPendingReview
+	case 5:
+		return errcode.ErrRegistrationRejected
+	default:
+		return errcode.ErrRegistrationNotPayable
+	}
+}
+
+// Approve 审核通过报名：收费报名转为待支付，免费报名直接确认
+func (s *RegistrationService) Approve(ctx context.Context, id int64, operatorID int64) error {
+	return s.review(ctx, id, operatorID, true, "")
+}
+
+// Reject 审核拒绝报名，释放名额并记录原因
+func (s *RegistrationService) Reject(ctx context.Context, id int64, operatorID int64, reason string) error {
+	return s.review(ctx, id, operatorID, false, reason)
+}
+
+// review 处理报名审核结果，团体报名同步调整订单金额和状态
+func (s *RegistrationService) review(ctx context.Context, id int64, operatorID int64, approved bool, reason string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reg model.Registration
+		if err := tx.First(&reg, id).Error; err != nil {
+			return errcode.ErrRegistrationNotFound
+		}
+
+		if reg.Status != 4 {
+			return errcode.ErrRegistrationNotPendingReview
+		}
+
+		status := 5 // 审核未通过
+		if approved {
+			status = 0 // 待支付
+			// 免费报名直接确认
+			if reg.Amount == 0 {
+				status = 1
+			}
+		}
+
+		now := time.Now()
+		res := tx.Model(&model.Registration{}).
+			Where("id = ? AND status = 4", id).
+			Updates(map[string]any{
+				"status":        status,
+				"review_reason": reason,
+				"reviewed_at":   &now,
+				"reviewed_by":   operatorID,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errcode.ErrRegistrationNotPendingReview
+		}
+
+		if reg.OrderID == nil || approved {
+			return nil
+		}
+
+		// 团体报名中被拒绝的报名人从订单金额中扣除，全部被拒绝或取消时关闭订单
+		var order model.RegistrationOrder
+		if err := tx.First(&order, *reg.OrderID).Error; err != nil {
+			return errcode.ErrOrderNotFound
+		}
+
+		var remaining int64
+		tx.Model(&model.Registration{}).
+			Where("order_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", order.ID).
+			Count(&remaining)
+
+		total := order.TotalAmount - reg.Amount
+		updates := map[string]any{"total_amount": total}
+		if remaining == 0 {
+			updates["status"] = 2
+		} else if total == 0 && order.Status == 0 {
+			updates["status"] = 1 // 剩余报名人均免费，无需支付
+		}
+		return tx.Model(&order).Updates(updates).Error
+	})
+}
+
+// AdminCreateRegistrationRequest 后台代报名请求（线下报名录入）
+type AdminCreateRegistrationRequest struct {
+	ActivityID int64 `json:"activity_id" binding:"required"`
+	UserID     int64 `json:"user_id"` // 关联的小程序用户，线下报名可为空
+	Paid       bool  `json:"paid"`    // 是否已线下收款
+	ParticipantRequest
+}
+
+// AdminCreate 后台代报名
+// 沿用名额、票种和重复报名校验，但不受报名时间限制，且由操作人直接完成审核
+func (s *RegistrationService) AdminCreate(ctx context.Context, operatorID int64, req *AdminCreateRegistrationRequest) (*model.Registration, error) {
+	var reg *model.Registration
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activity, err := lockActivity(tx, req.ActivityID)
+		if err != nil {
+			return err
+		}
+
+		// 关联小程序用户时同样校验用户是否重复报名
+		if req.UserID > 0 {
+			var existCount int64
+			tx.Model(&model.Registration{}).
+				Where("activity_id = ? AND user_id = ? AND status IN (0,1,4) AND deleted_at IS NULL", req.ActivityID, req.UserID).
+				Count(&existCount)
+			if existCount > 0 {
+				return errcode.ErrAlreadyRegistered
+			}
+		}
+
+		regs, err := createParticipants(tx, activity, req.UserID, nil, []ParticipantRequest{req.ParticipantRequest})
+		if err != nil {
+			return err
+		}
+		reg = &regs[0]
+
+		updates := map[string]any{"created_by": operatorID}
+		reg.CreatedBy = &operatorID
+
+		// 后台录入的报名视为已审核通过
+		if reg.Status == 4 {
+			now := time.Now()
+			reg.Status = 0
+			if reg.Amount == 0 {
+				reg.Status = 1
+			}
+			reg.ReviewedAt = &now
+			reg.ReviewedBy = &operatorID
+			updates["status"] = reg.Status
+			updates["reviewed_at"] = &now
+			updates["reviewed_by"] = operatorID
+		}
+		if err := tx.Model(reg).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if req.Paid && reg.Status == 0 {
+			return s.markPaidOffline(tx, reg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// UpdateRegistrationRequest 后台修改报名人信息请求
+type UpdateRegistrationRequest struct {
+	Name      string          `json:"name" binding:"required"`
+	Phone     string          `json:"phone" binding:"required"`
+	IDCard    string          `json:"id_card"`
+	ExtraInfo json.RawMessage `json:"extra_info"` // 为空时保留原附加信息
+}
+
+// AdminUpdate 后台修改报名人信息（更正姓名、证件号等录入错误）
+func (s *RegistrationService) AdminUpdate(ctx context.Context, id int64, req *UpdateRegistrationRequest) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reg model.Registration
+		if err := tx.First(&reg, id).Error; err != nil {
+			return errcode.ErrRegistrationNotFound
+		}
+
+		if _, err := lockActivity(tx, reg.ActivityID); err != nil {
+			return err
+		}
+
+		// 无敏感信息权限的后台用户拿到的是脱敏值，原样提交时保留原值
+		if req.Phone == mask.Phone(reg.Phone) {
+			req.Phone = reg.Phone
+		}
+		if req.IDCard != "" && req.IDCard == mask.IDCard(reg.IDCard) {
+			req.IDCard = reg.IDCard
+		}
+
+		// 有效报名修改后仍需满足重复报名规则
+		if reg.Status == 0 || reg.Status == 1 || reg.Status == 4 {
+			p := ParticipantRequest{Name: req.Name, Phone: req.Phone, IDCard: req.IDCard}
+			if err := checkDuplicateParticipant(tx, reg.ActivityID, &p, reg.ID); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]any{
+			"name":    req.Name,
+			"phone":   req.Phone,
+			"id_card": req.IDCard,
+		}
+		if len(req.ExtraInfo) > 0 {
+			updates["extra_info"] = req.ExtraInfo
+		}
+		return tx.Model(&reg).Updates(updates).Error
+	})
+}
+
+// AdminCancel 后台代为取消报名
+// 已支付的报名默认按活动退款规则退款，fullRefund 为 true 时全额退款
+func (s *RegistrationService) AdminCancel(ctx context.Context, id int64, fullRefund bool) error {
+	var reg model.Registration
+	if err := s.db.WithContext(ctx).First(&reg, id).Error; err != nil {
+		return errcode.ErrRegistrationNotFound
+	}
+	return s.cancel(ctx, &reg, fullRefund, "管理员代为取消报名")
+}
+
+// MarkPaidOffline 标记报名已线下收款（现金、转账等）
+func (s *RegistrationService) MarkPaidOffline(ctx context.Context, id int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reg model.Registration
+		if err := tx.First(&reg, id).Error; err != nil {
+			return errcode.ErrRegistrationNotFound
+		}
+
+		// 团体报名按订单整体支付
+		if reg.OrderID != nil {
+			return errcode.ErrRegistrationInOrder
+		}
+
+		if err := CheckRegistrationPayable(reg.Status); err != nil {
+			return err
+		}
+		return s.markPaidOffline(tx, &reg)
+	})
+}
+
+// markPaidOffline 在事务中为待支付报名生成线下支付记录并确认报名
+func (s *RegistrationService) markPaidOffline(tx *gorm.DB, reg *model.Registration) error {
+	updates := map[string]any{"status": 1}
+
+	if reg.Amount > 0 {
+		now := time.Now()
+		pay := model.Payment{
+			OrderNo: s.paymentSvc.GenerateOrderNo(),
+			UserID:  reg.UserID,
+			Amount:  reg.Amount,
+			PayType: "offline",
+			Status:  1,
+			BizType: "registration",
+			BizID:   reg.ID,
+			PaidAt:  &now,
+		}
+		if err := tx.Create(&pay).Error; err != nil {
+			return err
+		}
+		updates["payment_id"] = pay.ID
+		reg.PaymentID = &pay.ID
+	}
+
+	// 条件更新防止与在线支付回调并发重复确认
+	res := tx.Model(&model.Registration{}).
+		Where("id = ? AND status = 0", reg.ID).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errcode.ErrRegistrationNotPayable
+	}
+	reg.Status = 1
+	return nil
 }
 
 // GetByUser 获取用户的报名列表（小程序端）
@@ -177,8 +715,9 @@ func (s *RegistrationService) GetByUser(ctx context.Context, userID int64, page,
 	)
 
 	db := s.db.WithContext(ctx).Table("registrations").
-		Select("registrations.*, activities.title as activity_title").
+		Select("registrations.*, activities.title as activity_title, activity_tickets.name as ticket_name").
 		Joins("LEFT JOIN activities ON registrations.activity_id = activities.id").
+		Joins("LEFT JOIN activity_tickets ON registrations.ticket_id = activity_tickets.id").
 		Where("registrations.user_id = ? AND registrations.deleted_at IS NULL", userID)
 
 	if err := db.Count(&total).Error; err != nil {
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/money"
+)
+
+// ReportService 财务报表服务
+//
+// 收入按支付时间（paid_at）计入，退款按退款成功时间（refunds.success_at）计入当期并从收入中扣除，
+// 即收付实现制：跨期退款冲减退款发生期间的收入。汇总均在数据库中按分组聚合完成
+type ReportService struct {
+	db *gorm.DB
+}
+
+// NewReportService 创建财务报表服务
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// 统计周期
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week" // 自然周，以周一日期标识
+	PeriodMonth = "month"
+)
+
+// maxReportDays 报表最大统计天数
+const maxReportDays = 366 * 3
+
+// ReportQuery 报表查询条件
+type ReportQuery struct {
+	Start   time.Time // 起始日期（含）
+	End     time.Time // 截止日期（含）
+	Period  string    // 统计周期，仅收入趋势使用
+	BizType string    // 业务类型，为空时不过滤
+}
+
+// ParseReportQuery 解析报表查询条件，日期格式为 2006-01-02
+// 未指定日期时统计本月 1 日至今天，未指定周期时按天统计
+func ParseReportQuery(start, end, period, bizType string) (*ReportQuery, error) {
+	now := time.Now()
+	q := &ReportQuery{
+		Start:   time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local),
+		End:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
+		Period:  period,
+		BizType: bizType,
+	}
+
+	var err error
+	if start != "" {
+		if q.Start, err = time.ParseInLocation("2006-01-02", start, time.Local); err != nil {
+			return nil, errcode.ErrReportRangeInvalid
+		}
+	}
+	if end != "" {
+		if q.End, err = time.ParseInLocation("2006-01-02", end, time.Local); err != nil {
+			return nil, errcode.ErrReportRangeInvalid
+		}
+	}
+	if q.End.Before(q.Start) || q.End.Sub(q.Start) > maxReportDays*24*time.Hour {
+		return nil, errcode.ErrReportRangeInvalid
+	}
+
+	switch q.Period {
+	case "":
+		q.Period = PeriodDay
+	case PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		return nil, errcode.ErrReportPeriodInvalid
+	}
+	return q, nil
+}
+
+// until 统计截止时间（截止日期次日零点，不含）
+func (q *ReportQuery) until() time.Time {
+	return q.End.AddDate(0, 0, 1)
+}
+
+// ReportAmounts 收入、退款金额及笔数
+type ReportAmounts struct {
+	PaidAmount   money.Money `json:"paid_amount"`   // 收入金额
+	PaidCount    int64       `json:"paid_count"`    // 支付笔数
+	RefundAmount money.Money `json:"refund_amount"` // 退款金额
+	RefundCount  int64       `json:"refund_count"`  // 退款笔数（退款流水数）
+	NetAmount    money.Money `json:"net_amount"`    // 净收入（收入扣除退款）
+}
+
+// ReportSummary 财务汇总
+type ReportSummary struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	ReportAmounts
+	RefundedOrders int64 `json:"refunded_orders"` // 期间内发生退款的支付订单数
+	FailedOrders   int64 `json:"failed_orders"`   // 期间内创建且支付失败（含关单）的订单数
+	PendingOrders  int64 `json:"pending_orders"`  // 期间内创建且仍待支付的订单数
+}
+
+// RevenueRow 收入趋势行
+type RevenueRow struct {
+	Period string `json:"period"` // 日期 2006-01-02、周一日期或月份 2006-01
+	ReportAmounts
+}
+
+// BizTypeRow 按业务类型的收入行
+type BizTypeRow struct {
+	BizType string `json:"biz_type"`
+	ReportAmounts
+}
+
+// ActivityRow 按活动的收入行（单人报名和团体报名订单）
+type ActivityRow struct {
+	ActivityID    int64  `json:"activity_id"`
+	ActivityTitle string `json:"activity_title"`
+	ReportAmounts
+}
+
+// amountRow 分组聚合结果
+type amountRow struct {
+	GroupKey string
+	Amount   money.Money
+	Count    int64
+}
+
+// paidQuery 期间内支付成功（含之后发生退款）的支付记录
+func (s *ReportService) paidQuery(ctx context.Context, q *ReportQuery) *gorm.DB {
+	db := s.db.WithContext(ctx).Table("payments").
+		Where("payments.deleted_at IS NULL AND payments.status IN (1,2,4)").
+		Where("payments.paid_at >= ? AND payments.paid_at < ?", q.Start, q.until())
+	if q.BizType != "" {
+		db = db.Where("payments.biz_type = ?", q.BizType)
+	}
+	return db
+}
+
+// refundQuery 期间内退款成功的退款流水（关联支付记录）
+func (s *ReportService) refundQuery(ctx context.Context, q *ReportQuery) *gorm.DB {
+	db := s.db.WithContext(ctx).Table("refunds").
+		Joins("INNER JOIN payments ON refunds.payment_id = payments.id").
+		Where("refunds.deleted_at IS NULL AND refunds.status = 1").
+		Where("refunds.success_at >= ? AND refunds.success_at < ?", q.Start, q.until())
+	if q.BizType != "" {
+		db = db.Where("payments.biz_type = ?", q.BizType)
+	}
+	return db
+}
+
+// createdQuery 期间内创建的在线支付订单
+func (s *ReportService) createdQuery(ctx context.Context, q *ReportQuery) *gorm.DB {
+	db := s.db.WithContext(ctx).Table("payments").
+		Where("payments.deleted_at IS NULL AND payments.pay_type <> ?", "offline").
+		Where("payments.created_at >= ? AND payments.created_at < ?", q.Start, q.until())
+	if q.BizType != "" {
+		db = db.Where("payments.biz_type = ?", q.BizType)
+	}
+	return db
+}
+
+// Summary 财务汇总：收入、退款、净收入及各状态订单数
+func (s *ReportService) Summary(ctx context.Context, q *ReportQuery) (*ReportSummary, error) {
+	summary := &ReportSummary{
+		Start: q.Start.Format("2006-01-02"),
+		End:   q.End.Format("2006-01-02"),
+	}
+
+	var paid, refunded amountRow
+	if err := s.paidQuery(ctx, q).
+		Select("COALESCE(SUM(payments.amount), 0) AS amount, COUNT(*) AS count").
+		Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+	if err := s.refundQuery(ctx, q).
+		Select("COALESCE(SUM(refunds.amount), 0) AS amount, COUNT(*) AS count").
+		Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+	summary.ReportAmounts = newReportAmounts(paid, refunded)
+
+	if err := s.refundQuery(ctx, q).
+		Select("COUNT(DISTINCT refunds.payment_id)").
+		Scan(&summary.RefundedOrders).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		Status int
+		Count  int64
+	}
+	if err := s.createdQuery(ctx, q).
+		Where("payments.status IN (0,3)").
+		Select("payments.status, COUNT(*) AS count").
+		Group("payments.status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		if c.Status == 0 {
+			summary.PendingOrders = c.Count
+		} else {
+			summary.FailedOrders = c.Count
+		}
+	}
+	return summary, nil
+}
+
+// Revenue 按日、周或月统计收入趋势，没有交易的周期不返回
+func (s *ReportService) Revenue(ctx context.Context, q *ReportQuery) ([]RevenueRow, error) {
+	paid, err := s.groupAmounts(s.paidQuery(ctx, q), s.periodExpr("payments.paid_at", q), "payments.amount")
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := s.groupAmounts(s.refundQuery(ctx, q), s.periodExpr("refunds.success_at", q), "refunds.amount")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := mergeKeys(paid, refunded)
+	sort.Strings(keys)
+	rows := make([]RevenueRow, len(keys))
+	for i, key := range keys {
+		rows[i] = RevenueRow{Period: key, ReportAmounts: newReportAmounts(paid[key], refunded[key])}
+	}
+	return rows, nil
+}
+
+// ByBizType 按业务类型统计收入，按净收入从高到低排序
+func (s *ReportService) ByBizType(ctx context.Context, q *ReportQuery) ([]BizTypeRow, error) {
+	paid, err := s.groupAmounts(s.paidQuery(ctx, q), "payments.biz_type", "payments.amount")
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := s.groupAmounts(s.refundQuery(ctx, q), "payments.biz_type", "refunds.amount")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := mergeKeys(paid, refunded)
+	rows := make([]BizTypeRow, len(keys))
+	for i, key := range keys {
+		rows[i] = BizTypeRow{BizType: key, ReportAmounts: newReportAmounts(paid[key], refunded[key])}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].NetAmount != rows[j].NetAmount {
+			return rows[i].NetAmount > rows[j].NetAmount
+		}
+		return rows[i].BizType < rows[j].BizType
+	})
+	return rows, nil
+}
+
+// activityJoins 关联支付对应的活动：单人报名经报名记录，团体报名经报名订单
+func activityJoins(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("LEFT JOIN registrations ON payments.biz_type = 'registration' AND registrations.id = payments.biz_id").
+		Joins("LEFT JOIN registration_orders ON payments.biz_type = 'registration_order' AND registration_orders.id = payments.biz_id").
+		Where("payments.biz_type IN ?", []string{"registration", "registration_order"})
+}
+
+// activityIDExpr 支付对应的活动 ID
+const activityIDExpr = "COALESCE(registrations.activity_id, registration_orders.activity_id, 0)"
+
+// ByActivity 按活动统计报名收入（不含捐赠），按净收入从高到低排序
+func (s *ReportService) ByActivity(ctx context.Context, q *ReportQuery) ([]ActivityRow, error) {
+	paid, err := s.groupAmounts(activityJoins(s.paidQuery(ctx, q)), activityIDExpr, "payments.amount")
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := s.groupAmounts(activityJoins(s.refundQuery(ctx, q)), activityIDExpr, "refunds.amount")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := mergeKeys(paid, refunded)
+	rows := make([]ActivityRow, len(keys))
+	ids := make([]int64, 0, len(keys))
+	for i, key := range keys {
+		id, _ := strconv.ParseInt(key, 10, 64)
+		rows[i] = ActivityRow{ActivityID: id, ReportAmounts: newReportAmounts(paid[key], refunded[key])}
+		ids = append(ids, id)
+	}
+
+	// 补充活动名称
+	var titles []struct {
+		ID    int64
+		Title string
+	}
+	if len(ids) > 0 {
+		if err := s.db.WithContext(ctx).Table("activities").
+			Select("id, title").
+			Where("id IN ?", ids).
+			Scan(&titles).Error; err != nil {
+			return nil, err
+		}
+	}
+	titleByID := make(map[int64]string, len(titles))
+	for _, t := range titles {
+		titleByID[t.ID] = t.Title
+	}
+	for i := range rows {
+		rows[i].ActivityTitle = titleByID[rows[i].ActivityID]
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].NetAmount != rows[j].NetAmount {
+			return rows[i].NetAmount > rows[j].NetAmount
+		}
+		return rows[i].ActivityID < rows[j].ActivityID
+	})
+	return rows, nil
+}
+
+// groupAmounts 按分组表达式汇总金额和笔数，返回 分组值 -> 汇总
+func (s *ReportService) groupAmounts(db *gorm.DB, groupExpr, amountColumn string) (map[string]amountRow, error) {
+	var rows []amountRow
+	err := db.
+		Select(fmt.Sprintf("%s AS group_key, COALESCE(SUM(%s), 0) AS amount, COUNT(*) AS count", groupExpr, amountColumn)).
+		Group(groupExpr).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]amountRow, len(rows))
+	for _, r := range rows {
+		result[r.GroupKey] = r
+	}
+	return result, nil
+}
+
+// periodExpr 返回将时间列换算为本地时间后按统计周期取值的 SQL 表达式
+// 时间列按 UTC 偏移量换算为本地时间，偏移量取统计起始日期所在时区（不处理期间内的夏令时切换）
+func (s *ReportService) periodExpr(column string, q *ReportQuery) string {
+	_, offset := q.Start.Zone()
+
+	if s.db.Dialector.Name() == "postgres" {
+		local := fmt.Sprintf("((%s AT TIME ZONE 'UTC') + INTERVAL '%d seconds')", column, offset)
+		switch q.Period {
+		case PeriodWeek:
+			return fmt.Sprintf("to_char(date_trunc('week', %s), 'YYYY-MM-DD')", local)
+		case PeriodMonth:
+			return fmt.Sprintf("to_char(%s, 'YYYY-MM')", local)
+		}
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", local)
+	}
+
+	// SQLite 的日期函数将带时区的时间文本换算为 UTC，再加上偏移量得到本地时间
+	switch q.Period {
+	case PeriodWeek:
+		// weekday 0 前进到本周日（当天为周日时不变），再回退 6 天即为周一
+		return fmt.Sprintf("date(%s, '%+d seconds', 'weekday 0', '-6 days')", column, offset)
+	case PeriodMonth:
+		return fmt.Sprintf("strftime('%%Y-%%m', %s, '%+d seconds')", column, offset)
+	}
+	return fmt.Sprintf("date(%s, '%+d seconds')", column, offset)
+}
+
+// newReportAmounts 由收入和退款汇总计算净收入
+func newReportAmounts(paid, refunded amountRow) ReportAmounts {
+	return ReportAmounts{
+		PaidAmount:   paid.Amount,
+		PaidCount:    paid.Count,
+		RefundAmount: refunded.Amount,
+		RefundCount:  refunded.Count,
+		NetAmount:    paid.Amount - refunded.Amount,
+	}
+}
+
+// mergeKeys 合并两个汇总结果的分组值
+func mergeKeys(a, b map[string]amountRow) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// 报表类型，用于导出
+const (
+	ReportSummaryType  = "summary"
+	ReportRevenueType  = "revenue"
+	ReportBizTypeType  = "biz_type"
+	ReportActivityType = "activity"
+)
+
+// bizTypeText 业务类型文字
+var bizTypeText = map[string]string{
+	"registration":       "单人报名",
+	"registration_order": "团体报名",
+	"donation":           "捐赠",
+}
+
+// amountHeader 金额列表头
+var amountHeader = []string{"收入金额", "支付笔数", "退款金额", "退款笔数", "净收入"}
+
+// cells 金额列文本
+func (a ReportAmounts) cells() []string {
+	return []string{
+		a.PaidAmount.String(),
+		strconv.FormatInt(a.PaidCount, 10),
+		a.RefundAmount.String(),
+		strconv.FormatInt(a.RefundCount, 10),
+		a.NetAmount.String(),
+	}
+}
+
+// Export 生成指定类型报表的表格（首行为表头），返回表格和文件名前缀
+func (s *ReportService) Export(ctx context.Context, reportType string, q *ReportQuery) ([][]string, string, error) {
+	var table [][]string
+	switch reportType {
+	case ReportSummaryType:
+		summary, err := s.Summary(ctx, q)
+		if err != nil {
+			return nil, "", err
+		}
+		header := append([]string{"起始日期", "截止日期"}, amountHeader...)
+		header = append(header, "退款订单数", "支付失败订单数", "待支付订单数")
+		row := append([]string{summary.Start, summary.End}, summary.cells()...)
+		row = append(row,
+			strconv.FormatInt(summary.RefundedOrders, 10),
+			strconv.FormatInt(summary.FailedOrders, 10),
+			strconv.FormatInt(summary.PendingOrders, 10),
+		)
+		table = [][]string{header, row}
+	case ReportRevenueType:
+		rows, err := s.Revenue(ctx, q)
+		if err != nil {
+			return nil, "", err
+		}
+		table = append(table, append([]string{"周期"}, amountHeader...))
+		for _, r := range rows {
+			table = append(table, append([]string{r.Period}, r.cells()...))
+		}
+	case ReportBizTypeType:
+		rows, err := s.ByBizType(ctx, q)
+		if err != nil {
+			return nil, "", err
+		}
+		table = append(table, append([]string{"业务类型"}, amountHeader...))
+		for _, r := range rows {
+			name := bizTypeText[r.BizType]
+			if name == "" {
+				name = r.BizType
+			}
+			table = append(table, append([]string{name}, r.cells()...))
+		}
+	case ReportActivityType:
+		rows, err := s.ByActivity(ctx, q)
+		if err != nil {
+			return nil, "", err
+		}
+		table = append(table, append([]string{"活动ID", "活动"}, amountHeader...))
+		for _, r := range rows {
+			table = append(table, append([]string{strconv.FormatInt(r.ActivityID, 10), r.ActivityTitle}, r.cells()...))
+		}
+	default:
+		return nil, "", errcode.ErrReportTypeInvalid
+	}
+
+	name := fmt.Sprintf("financial_%s_%s_%s", reportType, q.Start.Format("20060102"), q.End.Format("20060102"))
+	return table, name, nil
+}
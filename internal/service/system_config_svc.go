@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"sync"
 
@@ -9,27 +10,58 @@ import (
 	"gorm.io/gorm/clause"
 
 	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/crypto"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
 	"github.com/zzhtl/go-mountain/internal/repository"
 )
 
+// SecretMask 敏感配置在接口中的掩码，保存时值为掩码表示保持原值不变
+const SecretMask = "******"
+
 // SystemConfigService 系统配置服务
 type SystemConfigService struct {
-	repo  *repository.BaseRepo[model.SystemConfig]
-	db    *gorm.DB
-	cache sync.Map // 内存缓存，避免频繁读库
+	repo   *repository.BaseRepo[model.SystemConfig]
+	db     *gorm.DB
+	cache  sync.Map       // 内存缓存（敏感配置为解密后的明文），避免频繁读库
+	cipher *crypto.Cipher // 敏感配置加密器，未配置主密钥时为空，此时不能保存敏感配置
 }
 
-// NewSystemConfigService 创建系统配置服务
-func NewSystemConfigService(db *gorm.DB) *SystemConfigService {
+// NewSystemConfigService 创建系统配置服务，cipher 为敏感配置加密器（未配置主密钥时传 nil）
+func NewSystemConfigService(db *gorm.DB, cipher *crypto.Cipher) *SystemConfigService {
 	svc := &SystemConfigService{
-		repo: repository.NewBaseRepo[model.SystemConfig](db),
-		db:   db,
+		repo:   repository.NewBaseRepo[model.SystemConfig](db),
+		db:     db,
+		cipher: cipher,
 	}
 	// 启动时预加载所有配置到缓存
 	svc.ReloadCache(context.Background())
 	return svc
 }
 
+// isSecret 判断配置是否为敏感配置，已知敏感配置项即使历史数据的类型不是 secret 也按敏感配置处理
+func isSecret(c *model.SystemConfig) bool {
+	if c.Type == ConfigTypeSecret {
+		return true
+	}
+	sc, ok := schemaByKey[c.Key]
+	return ok && sc.Type == ConfigTypeSecret
+}
+
+// plainValue 返回配置的明文值，密文无法解密（未配置或配置了错误的主密钥）时返回空字符串
+func (s *SystemConfigService) plainValue(c *model.SystemConfig) string {
+	if !crypto.IsEncrypted(c.Value) {
+		return c.Value
+	}
+	if s.cipher == nil {
+		return ""
+	}
+	plain, err := s.cipher.Decrypt(c.Value, c.Key)
+	if err != nil {
+		return ""
+	}
+	return plain
+}
+
 // ReloadCache 重新加载缓存
 func (s *SystemConfigService) ReloadCache(ctx context.Context) {
 	var configs []model.SystemConfig
@@ -37,12 +69,12 @@ func (s *SystemConfigService) ReloadCache(ctx context.Context) {
 		return
 	}
 	s.cache = sync.Map{}
-	for _, c := range configs {
-		s.cache.Store(c.Key, c.Value)
+	for i := range configs {
+		s.cache.Store(configs[i].Key, s.plainValue(&configs[i]))
 	}
 }
 
-// GetValue 获取配置值（优先缓存）
+// GetValue 获取配置值（优先缓存），敏感配置返回解密后的明文
 func (s *SystemConfigService) GetValue(ctx context.Context, key string) string {
 	if v, ok := s.cache.Load(key); ok {
 		return v.(string)
@@ -51,78 +83,179 @@ func (s *SystemConfigService) GetValue(ctx context.Context, key string) string {
 	if err := s.db.WithContext(ctx).Where("`key` = ?", key).First(&config).Error; err != nil {
 		return ""
 	}
-	s.cache.Store(key, config.Value)
-	return config.Value
+	value := s.plainValue(&config)
+	s.cache.Store(key, value)
+	return value
 }
 
-// GetValueInt 获取整型配置值
-func (s *SystemConfigService) GetValueInt(ctx context.Context, key string, defaultVal int) int {
-	v := s.GetValue(ctx, key)
-	if v == "" {
-		return defaultVal
+// GetString 获取字符串配置值，未设置时返回配置项定义的默认值
+func (s *SystemConfigService) GetString(ctx context.Context, key string) string {
+	if v := s.GetValue(ctx, key); v != "" {
+		return v
 	}
-	i, err := strconv.Atoi(v)
-	if err != nil {
-		return defaultVal
+	if sc, ok := schemaByKey[key]; ok {
+		return sc.Default
+	}
+	return ""
+}
+
+// GetInt 获取整数配置值，未设置或不是整数时返回配置项定义的默认值
+func (s *SystemConfigService) GetInt(ctx context.Context, key string) int {
+	if n, err := strconv.Atoi(s.GetValue(ctx, key)); err == nil {
+		return n
+	}
+	if sc, ok := schemaByKey[key]; ok {
+		n, _ := strconv.Atoi(sc.Default)
+		return n
 	}
-	return i
+	return 0
 }
 
-// GetValueBool 获取布尔配置值
-func (s *SystemConfigService) GetValueBool(ctx context.Context, key string) bool {
-	v := s.GetValue(ctx, key)
-	return v == "true" || v == "1"
+// GetBool 获取布尔配置值，未设置或不是 true/false 时返回配置项定义的默认值
+func (s *SystemConfigService) GetBool(ctx context.Context, key string) bool {
+	if b, err := strconv.ParseBool(s.GetValue(ctx, key)); err == nil {
+		return b
+	}
+	if sc, ok := schemaByKey[key]; ok {
+		return sc.Default == "true"
+	}
+	return false
 }
 
 // SetValue 设置配置值（Upsert）
+// 敏感配置加密后存储；值为 SecretMask 时保持原值不变，仅更新分组、备注等信息
 func (s *SystemConfigService) SetValue(ctx context.Context, key, value, typ, groupName, remark string) error {
-	config := model.SystemConfig{
+	return s.BatchSet(ctx, []model.SystemConfig{{
 		Key:       key,
 		Value:     value,
 		Type:      typ,
 		GroupName: groupName,
 		Remark:    remark,
-	}
-
-	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "key"}},
-		DoUpdates: clause.AssignmentColumns([]string{"value", "type", "group_name", "remark", "updated_at"}),
-	}).Create(&config).Error
-
-	if err == nil {
-		s.cache.Store(key, value)
-	}
-	return err
+	}})
 }
 
-// BatchSet 批量设置配置
+// BatchSet 批量设置配置，敏感配置的处理同 SetValue
+// 已知配置项的类型以定义为准并按定义校验，未知配置项按声明的类型校验；任一项未通过时整体不保存，返回 *ConfigValidationError
 func (s *SystemConfigService) BatchSet(ctx context.Context, configs []model.SystemConfig) error {
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	if err := normalizeConfigs(configs); err != nil {
+		return err
+	}
+
+	plains := make(map[string]string, len(configs))
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for _, c := range configs {
-			err := tx.Clauses(clause.OnConflict{
+			plain, err := s.prepareSecret(tx, &c)
+			if err != nil {
+				return err
+			}
+			err = tx.Clauses(clause.OnConflict{
 				Columns:   []clause.Column{{Name: "key"}},
 				DoUpdates: clause.AssignmentColumns([]string{"value", "type", "group_name", "remark", "updated_at"}),
 			}).Create(&c).Error
 			if err != nil {
 				return err
 			}
-			s.cache.Store(c.Key, c.Value)
+			plains[c.Key] = plain
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// 事务提交后再更新缓存，避免回滚后缓存与数据库不一致
+	for key, plain := range plains {
+		s.cache.Store(key, plain)
+	}
+	return nil
 }
 
-// List 获取所有配置
+// normalizeConfigs 校验待保存的配置，并将已知配置项的类型设为定义的类型
+// 敏感配置提交掩码表示保持原值，不做校验
+func normalizeConfigs(configs []model.SystemConfig) error {
+	var fields []ConfigFieldError
+	for i := range configs {
+		c := &configs[i]
+		if c.Type == "" {
+			c.Type = ConfigTypeString
+		}
+
+		var msg string
+		sc, known := schemaByKey[c.Key]
+		switch {
+		case known:
+			c.Type = sc.Type
+			if c.Type != ConfigTypeSecret || c.Value != SecretMask {
+				msg = sc.validate(c.Value)
+			}
+		case c.Type != ConfigTypeSecret || c.Value != SecretMask:
+			msg = validateConfig(c.Type, c.Value)
+		}
+		if msg != "" {
+			fields = append(fields, ConfigFieldError{Key: c.Key, Message: msg})
+		}
+	}
+	if len(fields) > 0 {
+		return &ConfigValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// prepareSecret 保存前处理敏感配置，返回写入缓存的明文值
+// 已是敏感配置的项不能通过保存降级为普通类型；值为掩码时沿用数据库中的原值，非空的新值加密后存储
+func (s *SystemConfigService) prepareSecret(tx *gorm.DB, c *model.SystemConfig) (string, error) {
+	var existing model.SystemConfig
+	found := tx.Where("`key` = ?", c.Key).Limit(1).Find(&existing).RowsAffected > 0
+	if !isSecret(c) && !(found && isSecret(&existing)) {
+		return c.Value, nil
+	}
+	c.Type = ConfigTypeSecret
+
+	if c.Value == SecretMask {
+		c.Value = existing.Value
+		return s.plainValue(&existing), nil
+	}
+	if c.Value == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", errcode.ErrSecretKeyMissing
+	}
+	plain := c.Value
+	encrypted, err := s.cipher.Encrypt(plain, c.Key)
+	if err != nil {
+		return "", err
+	}
+	c.Value = encrypted
+	return plain, nil
+}
+
+// maskSecrets 将敏感配置的值替换为掩码（未设置时为空），用于接口返回
+func maskSecrets(configs []model.SystemConfig) {
+	for i := range configs {
+		if !isSecret(&configs[i]) {
+			continue
+		}
+		configs[i].Type = ConfigTypeSecret
+		if configs[i].Value != "" {
+			configs[i].Value = SecretMask
+		}
+	}
+}
+
+// List 获取所有配置，敏感配置的值以掩码返回
 func (s *SystemConfigService) List(ctx context.Context) ([]model.SystemConfig, error) {
 	var configs []model.SystemConfig
 	err := s.db.WithContext(ctx).Order("group_name, `key`").Find(&configs).Error
+	maskSecrets(configs)
 	return configs, err
 }
 
-// ListByGroup 按分组获取配置
+// ListByGroup 按分组获取配置，敏感配置的值以掩码返回
 func (s *SystemConfigService) ListByGroup(ctx context.Context, groupName string) ([]model.SystemConfig, error) {
 	var configs []model.SystemConfig
 	err := s.db.WithContext(ctx).Where("group_name = ?", groupName).Order("`key`").Find(&configs).Error
+	maskSecrets(configs)
 	return configs, err
 }
 
@@ -148,7 +281,7 @@ func (s *SystemConfigService) GetWechatPayConfig(ctx context.Context) map[string
 		"wechat.app_id", "wechat.secret",
 		"wechat.mch_id", "wechat.mch_api_v3_key",
 		"wechat.mch_serial_no", "wechat.mch_private_key",
-		"wechat.notify_url",
+		"wechat.notify_url", "wechat.refund_notify_url",
 	}
 	result := make(map[string]string, len(keys))
 	for _, k := range keys {
@@ -157,32 +290,104 @@ func (s *SystemConfigService) GetWechatPayConfig(ctx context.Context) map[string
 	return result
 }
 
-// InitDefaultConfigs 初始化默认配置项（如果不存在）
+// InitDefaultConfigs 按配置项定义初始化默认配置项（如果不存在）
 func (s *SystemConfigService) InitDefaultConfigs(ctx context.Context) {
-	defaults := []model.SystemConfig{
-		// 站点信息
-		{Key: "site.name", Value: "远山公益", Type: "string", GroupName: "站点信息", Remark: "站点名称"},
-		{Key: "site.description", Value: "远山公益管理平台", Type: "string", GroupName: "站点信息", Remark: "站点描述"},
+	for _, sc := range configSchemas {
+		// 仅在 key 不存在时插入
+		var count int64
+		s.db.WithContext(ctx).Model(&model.SystemConfig{}).Where("`key` = ?", sc.Key).Count(&count)
+		if count == 0 {
+			s.db.WithContext(ctx).Create(&model.SystemConfig{
+				Key:       sc.Key,
+				Value:     sc.Default,
+				Type:      sc.Type,
+				GroupName: sc.GroupName,
+				Remark:    sc.Description,
+			})
+			s.cache.Store(sc.Key, sc.Default)
+		}
+	}
+}
 
-		// 微信小程序
-		{Key: "wechat.app_id", Value: "", Type: "string", GroupName: "微信配置", Remark: "小程序 AppID"},
-		{Key: "wechat.secret", Value: "", Type: "string", GroupName: "微信配置", Remark: "小程序 Secret"},
+// EncryptPlainSecrets 加密历史明文存储的敏感配置，并将内置敏感配置项的类型标记为 secret，返回加密的配置数
+// 未配置主密钥时不做处理；可重复执行
+func (s *SystemConfigService) EncryptPlainSecrets(ctx context.Context) (int, error) {
+	if s.cipher == nil {
+		return 0, nil
+	}
 
-		// 微信支付
-		{Key: "wechat.mch_id", Value: "", Type: "string", GroupName: "微信支付", Remark: "商户号"},
-		{Key: "wechat.mch_api_v3_key", Value: "", Type: "string", GroupName: "微信支付", Remark: "APIv3 密钥"},
-		{Key: "wechat.mch_serial_no", Value: "", Type: "string", GroupName: "微信支付", Remark: "商户证书序列号"},
-		{Key: "wechat.mch_private_key", Value: "", Type: "string", GroupName: "微信支付", Remark: "商户私钥内容（PEM 格式）"},
-		{Key: "wechat.notify_url", Value: "", Type: "string", GroupName: "微信支付", Remark: "支付回调地址（如 https://example.com/api/payment/wechat/notify）"},
+	var configs []model.SystemConfig
+	if err := s.db.WithContext(ctx).Find(&configs).Error; err != nil {
+		return 0, err
 	}
 
-	for _, d := range defaults {
-		// 仅在 key 不存在时插入
-		var count int64
-		s.db.WithContext(ctx).Model(&model.SystemConfig{}).Where("`key` = ?", d.Key).Count(&count)
-		if count == 0 {
-			s.db.WithContext(ctx).Create(&d)
-			s.cache.Store(d.Key, d.Value)
+	count := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range configs {
+			if !isSecret(&c) || (c.Type == ConfigTypeSecret && (c.Value == "" || crypto.IsEncrypted(c.Value))) {
+				continue
+			}
+			updates := map[string]any{"type": ConfigTypeSecret}
+			if c.Value != "" && !crypto.IsEncrypted(c.Value) {
+				encrypted, err := s.cipher.Encrypt(c.Value, c.Key)
+				if err != nil {
+					return err
+				}
+				updates["value"] = encrypted
+				count++
+			}
+			if err := tx.Model(&model.SystemConfig{}).Where("id = ?", c.ID).Updates(updates).Error; err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	return count, err
+}
+
+// RotateSecrets 使用新主密钥重新加密全部敏感配置，返回重新加密的配置数
+// 在同一事务中完成，任一配置无法用当前主密钥解密时整体回滚；完成后服务改用新主密钥
+func (s *SystemConfigService) RotateSecrets(ctx context.Context, next *crypto.Cipher) (int, error) {
+	var configs []model.SystemConfig
+	if err := s.db.WithContext(ctx).Find(&configs).Error; err != nil {
+		return 0, err
 	}
+
+	count := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range configs {
+			if !isSecret(&c) || c.Value == "" {
+				continue
+			}
+			plain := c.Value
+			if crypto.IsEncrypted(c.Value) {
+				if s.cipher == nil {
+					return errcode.ErrSecretKeyMissing
+				}
+				var err error
+				if plain, err = s.cipher.Decrypt(c.Value, c.Key); err != nil {
+					return fmt.Errorf("配置 %s: %w", c.Key, err)
+				}
+			}
+			encrypted, err := next.Encrypt(plain, c.Key)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.SystemConfig{}).Where("id = ?", c.ID).Updates(map[string]any{
+				"type":  ConfigTypeSecret,
+				"value": encrypted,
+			}).Error; err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.cipher = next
+	s.ReloadCache(ctx)
+	return count, nil
 }
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/zzhtl/go-mountain/internal/model"
+	"github.com/zzhtl/go-mountain/internal/pkg/errcode"
+	"github.com/zzhtl/go-mountain/internal/pkg/mask"
+	"github.com/zzhtl/go-mountain/internal/pkg/pdf"
+	"github.com/zzhtl/go-mountain/internal/repository"
+)
+
+// 志愿时长来源
+const (
+	VolunteerSourceAttendance = "attendance"
+	VolunteerSourceManual     = "manual"
+)
+
+// VolunteerHourService 志愿服务时长服务
+type VolunteerHourService struct {
+	repo      *repository.BaseRepo[model.VolunteerHour]
+	db        *gorm.DB
+	configSvc *SystemConfigService
+}
+
+// NewVolunteerHourService 创建志愿服务时长服务
+func NewVolunteerHourService(db *gorm.DB, configSvc *SystemConfigService) *VolunteerHourService {
+	return &VolunteerHourService{
+		repo:      repository.NewBaseRepo[model.VolunteerHour](db),
+		db:        db,
+		configSvc: configSvc,
+	}
+}
+
+// VolunteerHourListItem 时长流水列表项
+type VolunteerHourListItem struct {
+	model.VolunteerHour
+	UserName      string `json:"user_name,omitempty"`
+	ActivityTitle string `json:"activity_title"`
+}
+
+// listQuery 时长流水基础查询（关联用户和活动）
+func (s *VolunteerHourService) listQuery(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table("volunteer_hours").
+		Select("volunteer_hours.*, users.name as user_name, activities.title as activity_title").
+		Joins("LEFT JOIN users ON volunteer_hours.user_id = users.id").
+		Joins("LEFT JOIN activities ON volunteer_hours.activity_id = activities.id").
+		Where("volunteer_hours.deleted_at IS NULL")
+}
+
+// List 获取时长流水（后台管理），source 为空时不过滤
+func (s *VolunteerHourService) List(ctx context.Context, page, pageSize int, userID, activityID int64, source string) ([]VolunteerHourListItem, int64, error) {
+	var (
+		list  []VolunteerHourListItem
+		total int64
+	)
+
+	db := s.listQuery(ctx)
+	if userID > 0 {
+		db = db.Where("volunteer_hours.user_id = ?", userID)
+	}
+	if activityID > 0 {
+		db = db.Where("volunteer_hours.activity_id = ?", activityID)
+	}
+	if source != "" {
+		db = db.Where("volunteer_hours.source = ?", source)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("volunteer_hours.service_date DESC, volunteer_hours.id DESC").
+		Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// CreateVolunteerHourRequest 手工录入时长请求，负数时长用于冲正
+type CreateVolunteerHourRequest struct {
+	UserID      int64      `json:"user_id" binding:"required"`
+	ActivityID  *int64     `json:"activity_id"`
+	Hours       float64    `json:"hours" binding:"required,min=-1000,max=1000"`
+	ServiceDate *time.Time `json:"service_date"`
+	Remark      string     `json:"remark" binding:"max=500"`
+}
+
+// Create 手工录入志愿时长（后台）
+func (s *VolunteerHourService) Create(ctx context.Context, operatorID int64, req *CreateVolunteerHourRequest) (*model.VolunteerHour, error) {
+	hours := roundHours(req.Hours)
+	if hours == 0 {
+		return nil, errcode.ErrVolunteerHoursInvalid
+	}
+
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, req.UserID).Error; err != nil {
+		return nil, errcode.ErrNotFound
+	}
+
+	serviceDate := time.Now()
+	if req.ServiceDate != nil {
+		serviceDate = *req.ServiceDate
+	}
+
+	if req.ActivityID != nil {
+		var activity model.Activity
+		if err := s.db.WithContext(ctx).First(&activity, *req.ActivityID).Error; err != nil {
+			return nil, errcode.ErrNotFound
+		}
+		if req.ServiceDate == nil {
+			serviceDate = activity.StartTime
+		}
+	}
+
+	entry := &model.VolunteerHour{
+		UserID:      user.ID,
+		ActivityID:  req.ActivityID,
+		Hours:       hours,
+		ServiceDate: serviceDate,
+		Source:      VolunteerSourceManual,
+		Remark:      req.Remark,
+		CreatedBy:   &operatorID,
+	}
+	if err := s.repo.Create(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Delete 删除时长记录
+// 物理删除，签到来源的记录删除后可重新结算
+func (s *VolunteerHourService) Delete(ctx context.Context, id int64) error {
+	res := s.db.WithContext(ctx).Unscoped().Delete(&model.VolunteerHour{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errcode.ErrVolunteerHourNotFound
+	}
+	return nil
+}
+
+// SettleRequest 按签到结算活动时长请求，Hours 为 0 时按活动起止时间计算
+type SettleRequest struct {
+	ActivityID int64   `json:"activity_id" binding:"required"`
+	Hours      float64 `json:"hours" binding:"min=0,max=1000"`
+}
+
+// Settle 为活动中已签到的确认报名记入志愿时长
+// 时长记在报名用户名下，同一用户在一个活动中只记一次（团体报名的各报名人都属于下单用户，不重复累计），
+// 优先关联本人单独报名的记录，其次为团体订单中最早的报名人；重复结算时跳过已入账的用户，返回本次新增的记录数
+func (s *VolunteerHourService) Settle(ctx context.Context, operatorID int64, req *SettleRequest) (int64, error) {
+	var activity model.Activity
+	if err := s.db.WithContext(ctx).First(&activity, req.ActivityID).Error; err != nil {
+		return 0, errcode.ErrNotFound
+	}
+
+	hours := roundHours(req.Hours)
+	if hours == 0 {
+		hours = roundHours(activity.EndTime.Sub(activity.StartTime).Hours())
+	}
+	if hours <= 0 {
+		return 0, errcode.ErrVolunteerHoursInvalid
+	}
+
+	var regs []model.Registration
+	err := s.db.WithContext(ctx).
+		Where("activity_id = ? AND status = 1 AND checked_in_at IS NOT NULL AND user_id > 0", activity.ID).
+		Where("NOT EXISTS (SELECT 1 FROM volunteer_hours WHERE volunteer_hours.user_id = registrations.user_id AND volunteer_hours.activity_id = registrations.activity_id AND volunteer_hours.source = ? AND volunteer_hours.deleted_at IS NULL)", VolunteerSourceAttendance).
+		Order("CASE WHEN order_id IS NULL THEN 0 ELSE 1 END, id").
+		Find(&regs).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(regs) == 0 {
+		return 0, nil
+	}
+
+	entries := make([]model.VolunteerHour, 0, len(regs))
+	settled := make(map[int64]bool, len(regs))
+	for _, reg := range regs {
+		if settled[reg.UserID] {
+			continue
+		}
+		settled[reg.UserID] = true
+		entries = append(entries, model.VolunteerHour{
+			UserID:         reg.UserID,
+			ActivityID:     &activity.ID,
+			RegistrationID: &reg.ID,
+			Hours:          hours,
+			ServiceDate:    activity.StartTime,
+			Source:         VolunteerSourceAttendance,
+			CreatedBy:      &operatorID,
+		})
+	}
+
+	// 唯一索引兜底并发结算
+	res := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entries)
+	return res.RowsAffected, res.Error
+}
+
+// VolunteerTotal 用户累计时长
+type VolunteerTotal struct {
+	UserID          int64      `json:"user_id"`
+	UserName        string     `json:"user_name"`
+	Phone           string     `json:"phone"`
+	TotalHours      float64    `json:"total_hours"`
+	RecordCount     int64      `json:"record_count"`
+	LastServiceDate *time.Time `json:"last_service_date"`
+}
+
+// Totals 按用户汇总累计时长（后台），按累计时长降序
+func (s *VolunteerHourService) Totals(ctx context.Context, page, pageSize int, keyword string) ([]VolunteerTotal, int64, error) {
+	var (
+		list  []VolunteerTotal
+		total int64
+	)
+
+	db := s.db.WithContext(ctx).Table("volunteer_hours").
+		Select("volunteer_hours.user_id, users.name as user_name, users.phone, SUM(volunteer_hours.hours) as total_hours, COUNT(*) as record_count, MAX(volunteer_hours.service_date) as last_service_date").
+		Joins("LEFT JOIN users ON volunteer_hours.user_id = users.id").
+		Where("volunteer_hours.deleted_at IS NULL").
+		Group("volunteer_hours.user_id, users.name, users.phone")
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		db = db.Where("users.name LIKE ? OR users.phone LIKE ?", like, like)
+	}
+
+	if err := s.db.WithContext(ctx).Table("(?) as t", db).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("total_hours DESC, volunteer_hours.user_id").
+		Offset(offset).Limit(pageSize).Scan(&list).Error
+	return list, total, err
+}
+
+// VolunteerSummary 个人时长汇总
+type VolunteerSummary struct {
+	TotalHours  float64 `json:"total_hours"`
+	RecordCount int64   `json:"record_count"`
+}
+
+// Summary 获取用户累计时长
+func (s *VolunteerHourService) Summary(ctx context.Context, userID int64) (*VolunteerSummary, error) {
+	var summary VolunteerSummary
+	err := s.db.WithContext(ctx).Model(&model.VolunteerHour{}).
+		Select("COALESCE(SUM(hours), 0) as total_hours, COUNT(*) as record_count").
+		Where("user_id = ?", userID).
+		Scan(&summary).Error
+	summary.TotalHours = roundHours(summary.TotalHours)
+	return &summary, err
+}
+
+// GetByUser 获取用户的时长流水（小程序端）
+func (s *VolunteerHourService) GetByUser(ctx context.Context, userID int64, page, pageSize int) ([]VolunteerHourListItem, int64, error) {
+	var (
+		list  []VolunteerHourListItem
+		total int64
+	)
+
+	db := s.listQuery(ctx).Where("volunteer_hours.user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("volunteer_hours.service_date DESC, volunteer_hours.id DESC").
+		Offset(offset).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// IssueCertificate 为用户开具志愿服务证明
+// 累计时长和姓名与最近一次开具时相同则复用原证明，否则生成新的验证码
+func (s *VolunteerHourService) IssueCertificate(ctx context.Context, userID int64) (*model.VolunteerCertificate, error) {
+	summary, err := s.Summary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if summary.RecordCount == 0 || summary.TotalHours <= 0 {
+		return nil, errcode.ErrNoVolunteerHours
+	}
+
+	name, err := s.volunteerName(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var last model.VolunteerCertificate
+	err = s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").First(&last).Error
+	if err == nil && last.Name == name && last.RecordCount == summary.RecordCount &&
+		roundHours(last.TotalHours) == summary.TotalHours {
+		return &last, nil
+	}
+
+	code, err := newCertificateCode()
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &model.VolunteerCertificate{
+		Code:        code,
+		UserID:      userID,
+		Name:        name,
+		TotalHours:  summary.TotalHours,
+		RecordCount: summary.RecordCount,
+		IssuedAt:    time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(cert).Error; err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// volunteerName 证明上的姓名：优先取最近一次计入时长的报名人姓名，其次为用户昵称
+func (s *VolunteerHourService) volunteerName(ctx context.Context, userID int64) (string, error) {
+	var name string
+	err := s.db.WithContext(ctx).Table("volunteer_hours").
+		Select("registrations.name").
+		Joins("JOIN registrations ON volunteer_hours.registration_id = registrations.id").
+		Where("volunteer_hours.user_id = ? AND volunteer_hours.deleted_at IS NULL", userID).
+		Order("volunteer_hours.service_date DESC, volunteer_hours.id DESC").
+		Limit(1).
+		Scan(&name).Error
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		return name, nil
+	}
+
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return "", errcode.ErrNotFound
+	}
+	if user.Name == "" {
+		return "志愿者", nil
+	}
+	return user.Name, nil
+}
+
+// newCertificateCode 生成 16 位大写字母数字验证码
+func newCertificateCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf), nil
+}
+
+// CertificateVerification 证明核验结果，姓名脱敏展示
+type CertificateVerification struct {
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	TotalHours  float64   `json:"total_hours"`
+	RecordCount int64     `json:"record_count"`
+	IssuedAt    time.Time `json:"issued_at"`
+	Issuer      string    `json:"issuer"`
+}
+
+// Verify 按验证码核验证明（公开接口），忽略大小写和分隔符
+func (s *VolunteerHourService) Verify(ctx context.Context, code string) (*CertificateVerification, error) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	if code == "" {
+		return nil, errcode.ErrCertificateNotFound
+	}
+
+	var cert model.VolunteerCertificate
+	if err := s.db.WithContext(ctx).Where("code = ?", code).First(&cert).Error; err != nil {
+		return nil, errcode.ErrCertificateNotFound
+	}
+
+	return &CertificateVerification{
+		Code:        cert.Code,
+		Name:        mask.Name(cert.Name),
+		TotalHours:  roundHours(cert.TotalHours),
+		RecordCount: cert.RecordCount,
+		IssuedAt:    cert.IssuedAt,
+		Issuer:      s.configSvc.GetString(ctx, "site.name"),
+	}, nil
+}
+
+// WriteCertificatePDF 输出横版 A4 证明 PDF
+func (s *VolunteerHourService) WriteCertificatePDF(ctx context.Context, w io.Writer, cert *model.VolunteerCertificate) error {
+	issuer := s.configSvc.GetString(ctx, "site.name")
+
+	doc := pdf.New(pdf.A4Height, pdf.A4Width)
+	width, height := doc.Width(), doc.Height()
+	p := doc.AddPage()
+
+	p.SetStrokeColor(0.6, 0.1, 0.1)
+	p.Rect(24, 24, width-48, height-48, 3)
+	p.Rect(34, 34, width-68, height-68, 0.8)
+
+	p.SetFillColor(0.6, 0.1, 0.1)
+	p.TextCenter(height-130, 40, "志愿服务证明")
+
+	p.SetFillColor(0.1, 0.1, 0.1)
+	p.Text(110, height-210, 18, fmt.Sprintf("兹证明 %s 同志在%s累计参加志愿服务 %d 次，", cert.Name, issuer, cert.RecordCount))
+	p.Text(110, height-250, 18, fmt.Sprintf("服务时长共计 %s 小时。", formatHours(cert.TotalHours)))
+	p.Text(146, height-290, 18, "特此证明。")
+
+	right := width - 110
+	p.Text(right-pdf.TextWidth(issuer, 18), 150, 18, issuer)
+	date := cert.IssuedAt.Format("2006年01月02日")
+	p.Text(right-pdf.TextWidth(date, 16), 120, 16, date)
+
+	p.SetFillColor(0.4, 0.4, 0.4)
+	p.Text(70, 78, 11, "验证码："+cert.Code)
+	p.Text(70, 60, 11, "可在小程序中输入验证码核验本证明的真实性")
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// roundHours 时长保留两位小数
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+// formatHours 去掉时长末尾多余的 0，如 12.50 → 12.5
+func formatHours(h float64) string {
+	return strconv.FormatFloat(roundHours(h), 'f', -1, 64)
+}